@@ -0,0 +1,63 @@
+package datagen
+
+// Pos is a byte offset into the template source that a node was parsed from.
+type Pos int
+
+func (p Pos) Position() Pos {
+	return p
+}
+
+// Node is an element of the parse tree of a template.
+type Node interface {
+	Position() Pos
+}
+
+// Template is the parse tree of a full input as given to Gen.  Its nodes are
+// *TextNode (data outside of any block, which is copied as is) and *BlockNode.
+type Template struct {
+	Source  string
+	Markers MarkerOptions
+	Nodes   []Node
+}
+
+// TextNode is literal text.
+type TextNode struct {
+	Pos
+	Text string
+}
+
+// BlockNode is a datagen block, e.g. {{{ [[[ count: 3 ]]] {{ country }} }}}.
+// Body holds *TextNode, *ElementNode and nested *BlockNode values.
+type BlockNode struct {
+	Pos
+	Options *OptionsNode
+	Body    []Node
+}
+
+// OptionsNode holds the options of a block, e.g. [[[ count: 3 | separator: ',' ]]].
+type OptionsNode struct {
+	Pos
+	Options []Option
+}
+
+// ElementNode is a single element, e.g. {{ country | regex: ^C.* | random }}.
+// Name is the element type and Options are the parts following it.  As is
+// the name given with "country as c", by which other elements of the same row
+// can refer to its value.
+type ElementNode struct {
+	Pos
+	Name    string
+	As      string
+	Options []Option
+}
+
+// Option is one "key: value" part of an options or element list.  Key is
+// lower cased.  Value is the text following the first colon with any
+// enclosing quotes removed; it is empty for flags like "random".  Args holds
+// the same text split on colons that are not within quotes.
+type Option struct {
+	Pos
+	Key   string
+	Value string
+	Args  []string
+}
@@ -0,0 +1,156 @@
+package datagen
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// weighted is a list of values with a weight for each.  Values are picked in
+// proportion to their weights.
+type weighted struct {
+	values  []string
+	weights []float64
+	cum     []float64 //running totals of weights, for random picks
+}
+
+func newWeighted(values []string, weights []float64) *weighted {
+	w := &weighted{values: values, weights: weights, cum: make([]float64, len(weights))}
+	total := 0.0
+	for i, wt := range weights {
+		total += wt
+		w.cum[i] = total
+	}
+	return w
+}
+
+func (w *weighted) total() float64 {
+	if len(w.cum) == 0 {
+		return 0
+	}
+	return w.cum[len(w.cum)-1]
+}
+
+// random picks a value at random
+func (w *weighted) random(r *rand.Rand) string {
+	i := sort.SearchFloat64s(w.cum, r.Float64()*w.total())
+	//values with a weight of 0 are skipped
+	for i < len(w.cum)-1 && w.weights[i] == 0 {
+		i++
+	}
+	return w.values[i]
+}
+
+// next picks values in turn with a smooth weighted round robin.  Over a cycle
+// of total picks each value is picked as often as its weight, and the values
+// are spread out rather than bunched together.
+func (w *weighted) next(current []float64) string {
+	best := 0
+	for i, wt := range w.weights {
+		current[i] += wt
+		if current[i] > current[best] {
+			best = i
+		}
+	}
+	current[best] -= w.total()
+	return w.values[best]
+}
+
+// splitWeight splits a dictionary line like "Canada\t30" into its value and
+// weight.  Lines without a weight have a weight of 1.
+func splitWeight(line string) (string, float64, bool) {
+	i := strings.LastIndexByte(line, '\t')
+	if i < 0 {
+		return line, 1, false
+	}
+	wt, err := strconv.ParseFloat(strings.TrimSpace(line[i+1:]), 64)
+	if err != nil || wt < 0 {
+		return line, 1, false
+	}
+	return line[:i], wt, true
+}
+
+// choiceOptions are the options of the choice element, e.g.
+// {{ choice | values: "active,inactive,banned" | weights: "80,15,5" | random }}
+type choiceOptions struct {
+	Values  string //comma separated
+	Weights string //comma separated, one for each value
+	Random  bool
+}
+
+// choiceElement gives one of the values given inline.  Without random the
+// values are given in turn, as often as their weights, over and over.
+type choiceElement struct{}
+
+type choiceState struct {
+	opts    choiceOptions
+	w       *weighted
+	current []float64
+}
+
+func (choiceElement) Generate(ctx *Context) (string, error) {
+	st, ok := ctx.State.(*choiceState)
+	if !ok {
+		var err error
+		if st, err = prepareChoice(ctx); err != nil {
+			return "", err
+		}
+		ctx.State = st
+	}
+
+	if st.opts.Random {
+		return st.w.random(ctx.Rand), nil
+	}
+	return st.w.next(st.current), nil
+}
+
+func prepareChoice(ctx *Context) (*choiceState, error) {
+	st := &choiceState{}
+	if err := ctx.Decode(&st.opts); err != nil {
+		return nil, err
+	}
+
+	o := st.opts
+	if o.Values == "" {
+		return nil, ctx.OptionError("values", "no values to choose from")
+	}
+	values := strings.Split(o.Values, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+
+	weights := make([]float64, len(values))
+	if o.Weights == "" {
+		for i := range weights {
+			weights[i] = 1
+		}
+	} else {
+		ws := strings.Split(o.Weights, ",")
+		if len(ws) != len(values) {
+			return nil, ctx.OptionError("weights", "%d weights for %d values", len(ws), len(values))
+		}
+		for i, s := range ws {
+			wt, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || wt < 0 {
+				return nil, ctx.OptionError("weights", "bad weight %q", s)
+			}
+			weights[i] = wt
+		}
+	}
+
+	st.w = newWeighted(values, weights)
+	if st.w.total() <= 0 {
+		return nil, ctx.OptionError("weights", "the weights add up to 0")
+	}
+	st.current = make([]float64, len(values))
+	return st, nil
+}
+
+func (choiceElement) OptionNames() []string {
+	return optionNames(choiceOptions{})
+}
+
+func init() {
+	Register("choice", choiceElement{})
+}
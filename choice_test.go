@@ -0,0 +1,96 @@
+package datagen
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ChoiceElement_Sequential(t *testing.T) {
+	for _, tc := range []struct {
+		eb  string
+		exp string
+	}{
+		{`choice | values: "red,green,blue"`, "red,green,blue,red,green,blue"},
+		{`choice | values: "a,b" | weights: "2,1"`, "a,b,a,a,b,a"},
+		{`choice | values: "active,inactive,banned" | weights: "4,1,1"`, "active,active,inactive,active,banned,active"},
+		{`choice | values: "x,y" | weights: "0,1"`, "y,y,y,y,y,y"},
+	} {
+		s, err := NewGenerator(1).GenElement(tc.eb, 6)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		if strings.Join(s, ",") != tc.exp {
+			t.Errorf("FAIL. Expected %s for %s. Received %v.", tc.exp, tc.eb, s)
+		} else {
+			t.Logf("PASS. Expected %s for %s. Received %v.", tc.exp, tc.eb, s)
+		}
+	}
+}
+
+func Test_ChoiceElement_Random(t *testing.T) {
+	s, err := NewGenerator(1).GenElement(`choice | values: "active,inactive,banned" | weights: "80,15,5" | random`, 10000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	counts := map[string]int{}
+	for _, v := range s {
+		counts[v]++
+	}
+	if len(counts) != 3 || counts["active"] < 7500 || counts["active"] > 8500 || counts["banned"] < 300 || counts["banned"] > 700 {
+		t.Errorf("FAIL. Expected about 80%% active and 5%% banned. Received %v.", counts)
+	} else {
+		t.Logf("PASS. Received %v.", counts)
+	}
+}
+
+func Test_ChoiceElement_BadOptions(t *testing.T) {
+	expectBadOption(t,
+		"choice",
+		`choice | values: "a,b" | weights: "1"`,
+		`choice | values: "a,b" | weights: "1,x"`,
+		`choice | values: "a,b" | weights: "1,-1"`,
+		`choice | values: "a,b" | weights: "0,0"`,
+	)
+}
+
+func Test_Dictionary_Weights(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("market", []string{"Canada\t30", "Chile\t0", "Kenya\t10", "Japan"})
+
+	//in order the weights are honored as by choice, for a round of 4 values
+	//or over and over with cycle
+	g.RegisterDictionaryLines("grade", []string{"A\t3", "B\t1", "C\t0"})
+	for _, tc := range []struct {
+		eb    string
+		count int
+		exp   string
+	}{
+		{"grade", 5, "A,A,B,A"},
+		{"grade | cycle", 8, "A,A,B,A,A,A,B,A"},
+		{"choice | values: A,B,C | weights: 3,1,0", 8, "A,A,B,A,A,A,B,A"},
+		{"grade | unique", 2, "A,B"},
+	} {
+		s, err := g.GenElement(tc.eb, tc.count)
+		if err != nil || strings.Join(s, ",") != tc.exp {
+			t.Errorf("FAIL. Expected %s for %s. Received %v, %v.", tc.exp, tc.eb, s, err)
+		} else {
+			t.Logf("PASS. Expected %s for %s. Received %v.", tc.exp, tc.eb, s)
+		}
+	}
+
+	s, err := g.GenElement("market | random | regex: ^[CJ]", 3100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	counts := map[string]int{}
+	for _, v := range s {
+		counts[v]++
+	}
+	//Canada 30, Chile never, Japan 1 and Kenya filtered out
+	if counts["Chile"] != 0 || counts["Kenya"] != 0 || counts["Japan"] < 40 || counts["Japan"] > 180 {
+		t.Errorf("FAIL. Expected mostly Canada, some Japan. Received %v.", counts)
+	} else {
+		t.Logf("PASS. Received %v.", counts)
+	}
+}
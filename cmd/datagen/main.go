@@ -0,0 +1,148 @@
+// Command datagen generates data from a datagen template.
+//
+//	datagen [flags] [template]
+//	datagen [flags] --schema schema.yaml
+//
+// The template is read from the file given, or from stdin if there is none or
+// it is -.  The data is written to stdout, or to the file given with -o, as it
+// is generated.
+//
+//	datagen --markers=csv --seed=42 --count=1000 -o people.csv people.tmpl
+//
+// With --schema the data is described by a JSON or YAML schema file instead
+// of a template; see datagen.Schema.  --count overrides the count of its
+// tables, other than those whose rows are per row of another table, and
+// --markers is not used.
+//
+// --markers is one of default, csv, xml or dollar, or a custom marker set as
+// JSON, given inline or as the name of a .json file, e.g.
+//
+//	{"BlockBegin": "<<", "BlockEnd": ">>", "OptionsBegin": "[", "OptionsEnd": "]",
+//	 "ElementBegin": "<", "ElementEnd": ">", "Separator": "\n", "LastSeparator": "\n"}
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sathishvj/datagen"
+)
+
+var presets = map[string]datagen.MarkerOptions{
+	"default": datagen.DEFAULT,
+	"csv":     datagen.CSV,
+	"xml":     datagen.XML,
+	"dollar":  datagen.DOLLAR,
+}
+
+// markers gives the marker set named by the --markers flag.
+func markers(s string) (datagen.MarkerOptions, error) {
+	if mo, ok := presets[strings.ToLower(s)]; ok {
+		return mo, nil
+	}
+
+	b := []byte(s)
+	if !strings.HasPrefix(strings.TrimSpace(s), "{") {
+		var err error
+		if b, err = ioutil.ReadFile(s); err != nil {
+			return datagen.MarkerOptions{}, fmt.Errorf("markers must be default, csv, xml, dollar or JSON: %v", err)
+		}
+	}
+	var mo datagen.MarkerOptions
+	if err := json.Unmarshal(b, &mo); err != nil {
+		return mo, fmt.Errorf("bad markers: %v", err)
+	}
+	if mo.BlockBegin == "" || mo.BlockEnd == "" || mo.ElementBegin == "" || mo.ElementEnd == "" {
+		return mo, errors.New("bad markers: the block and element markers are needed")
+	}
+	return mo, nil
+}
+
+// run is the command with its arguments and files, and gives its exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("datagen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: datagen [flags] [template]\n       datagen [flags] --schema file")
+		fs.PrintDefaults()
+	}
+	markersFlag := fs.String("markers", "default", "marker set: default, csv, xml, dollar, or JSON inline or in a .json file")
+	seed := fs.Int64("seed", 0, "seed for the random numbers, for the same output every time (default random)")
+	count := fs.Int("count", 0, "number of rows of the outermost blocks, instead of their count options")
+	schema := fs.String("schema", "", "JSON or YAML schema `file` to generate from instead of a template")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 || *count < 0 || *schema != "" && fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	mo, err := markers(*markersFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, "datagen:", err)
+		return 2
+	}
+
+	seedGiven := false
+	fs.Visit(func(f *flag.Flag) { seedGiven = seedGiven || f.Name == "seed" })
+	if !seedGiven {
+		*seed = time.Now().UnixNano()
+	}
+	g := datagen.NewGenerator(*seed)
+	g.SetCount(*count)
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, "datagen:", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	if *schema != "" {
+		err = g.GenSchemaFile(bw, *schema)
+	} else {
+		err = generate(bw, g, fs.Arg(0), stdin, mo)
+	}
+	if err != nil {
+		bw.Flush()
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := bw.Flush(); err != nil {
+		fmt.Fprintln(stderr, "datagen:", err)
+		return 1
+	}
+	return 0
+}
+
+// generate writes the data for the template in fname, or in stdin if fname
+// is empty or -.
+func generate(w io.Writer, g *datagen.Generator, fname string, stdin io.Reader, mo datagen.MarkerOptions) error {
+	if fname != "" && fname != "-" {
+		return g.GenFileTo(w, fname, mo)
+	}
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	return g.GenTo(w, string(b), mo)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
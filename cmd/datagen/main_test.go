@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Run(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "people.tmpl")
+	if err := ioutil.WriteFile(filepath.Join(dir, "teams.txt"), []byte("red\nblue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := "id,name\n{{{ [[[ count: 2 | dictionary: team: teams.txt ]]] {{ seq }},{{ firstname }},{{ team | cycle }} }}}"
+	if err := ioutil.WriteFile(tmpl, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+		exp   string
+	}{
+		{[]string{tmpl}, "", "id,name\n1,AARON,red\n2,ABDUL,blue\n"},
+		{[]string{"--count", "3", tmpl}, "", "id,name\n1,AARON,red\n2,ABDUL,blue\n3,ABE,red\n"},
+		{[]string{"-"}, "{{{ [[[ count: 2 | separator: ';' ]]] {{ country }} }}}", "Afghanistan;Albania\n"},
+		{[]string{"--markers=csv"}, "{{ [count: 2] {country} }}", "Afghanistan,Albania\n"},
+		{[]string{"--markers", `{"BlockBegin": "<<", "BlockEnd": ">>", "ElementBegin": "<", "ElementEnd": ">", "LastSeparator": "."}`}, "<< <country> >>", "Afghanistan."},
+	} {
+		var stdout, stderr strings.Builder
+		code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+		if code != 0 || stdout.String() != tc.exp {
+			t.Errorf("FAIL. Expected %q for %v. Received %q, exit code %d, %s.", tc.exp, tc.args, stdout.String(), code, stderr.String())
+		} else {
+			t.Logf("PASS. Expected %q for %v. Received %q.", tc.exp, tc.args, stdout.String())
+		}
+	}
+}
+
+func Test_Run_SeedAndOutput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.txt")
+	tmpl := "{{{ [[[ count: 5 | separator: ',' ]]] {{ firstname | random }} }}}"
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--seed=42", "-o", out}, strings.NewReader(tmpl), &stdout, &stderr); code != 0 {
+		t.Fatalf("FAIL. Expected exit code 0. Received %d, %s.", code, stderr.String())
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 0 || len(strings.Split(string(b), ",")) != 5 {
+		t.Errorf("FAIL. Expected 5 names in the file only. Received %q and %q.", b, stdout.String())
+	}
+
+	//the same seed gives the same names
+	stdout.Reset()
+	run([]string{"--seed=42"}, strings.NewReader(tmpl), &stdout, &stderr)
+	if stdout.String() != string(b) {
+		t.Errorf("FAIL. Expected %q again. Received %q.", b, stdout.String())
+	} else {
+		t.Logf("PASS. Expected %q again.", b)
+	}
+}
+
+func Test_Run_Schema(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "people.yaml")
+	src := "name: people\ncount: 2\nformat: jsonl\nfields:\n  - {name: id, type: seq}\n  - {name: name, type: firstname, filters: [title]}\n"
+	if err := ioutil.WriteFile(schema, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		exp  string
+	}{
+		{[]string{"--schema", schema}, "{\"id\": 1, \"name\": \"Aaron\"}\n{\"id\": 2, \"name\": \"Abdul\"}\n"},
+		{[]string{"--count=1", "--schema", schema}, "{\"id\": 1, \"name\": \"Aaron\"}\n"},
+	} {
+		var stdout, stderr strings.Builder
+		code := run(tc.args, strings.NewReader(""), &stdout, &stderr)
+		if code != 0 || stdout.String() != tc.exp {
+			t.Errorf("FAIL. Expected %q for %v. Received %q, exit code %d, %s.", tc.exp, tc.args, stdout.String(), code, stderr.String())
+		} else {
+			t.Logf("PASS. Expected %q for %v. Received %q.", tc.exp, tc.args, stdout.String())
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.yaml")
+	if err := ioutil.WriteFile(bad, []byte("fields:\n  - {name: id, type: sek}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--schema", bad}, nil, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "fields[0].type: unknown element") {
+		t.Errorf("FAIL. Expected the place of the error. Received %d, %q.", code, stderr.String())
+	}
+}
+
+func Test_Run_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+		code  int
+		msg   string
+	}{
+		{[]string{"--markers=yaml"}, "", 2, "markers must be"},
+		{[]string{"--markers={"}, "", 2, "bad markers"},
+		{[]string{"--nope"}, "", 2, "flag provided but not defined"},
+		{[]string{"a", "b"}, "", 2, "usage"},
+		{[]string{"missing.tmpl"}, "", 1, "missing.tmpl"},
+		{nil, "{{{ {{ cuntry }} }}}", 1, "unknown element"},
+		{[]string{"--schema", "s.yaml", "a.tmpl"}, "", 2, "usage"},
+		{[]string{"--schema", "missing.yaml"}, "", 1, "missing.yaml"},
+	} {
+		var stdout, stderr strings.Builder
+		code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+		if code != tc.code || !strings.Contains(stderr.String(), tc.msg) {
+			t.Errorf("FAIL. Expected exit code %d and %q for %v. Received %d, %q.", tc.code, tc.msg, tc.args, code, stderr.String())
+		} else {
+			t.Logf("PASS. Expected exit code %d for %v. Received %q.", tc.code, tc.args, stderr.String())
+		}
+	}
+}
@@ -1,12 +1,11 @@
 package datagen
 
 import (
-	"bytes"
-	"fmt"
-	"io/ioutil"
-	"math/rand"
+	"bufio"
+	"io"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -15,6 +14,7 @@ type TextData struct {
 	MinSize int
 	MaxSize int
 	Count   int
+	Charset string //a named charset like alnum, or the characters to use. Letters if empty.
 }
 
 type MarkerOptions struct {
@@ -64,24 +64,27 @@ var numbers = "0123456789"
 var specialChars = "~!@#$%^&*()_-+=<>,./;:'\"[]{}\\|"
 
 func TextGen(ds TextData) []string {
+	return defaultGenerator().TextGen(ds)
+}
+
+func (g *Generator) TextGen(ds TextData) []string {
+	chars := []rune(charsetChars(ds.Charset))
 	var a []string
 	for c := 0; c < ds.Count; c++ {
-		str := ""
-		size := ds.MinSize + rand.Intn(ds.MaxSize-ds.MinSize+1)
-		for i := 0; i < size; i++ {
-			pos := rand.Intn(len(letters))
-			str = str + string(letters[pos])
-		}
-		a = append(a, str)
+		a = append(a, randomText(g.rand, ds.MinSize, ds.MaxSize, chars))
 	}
 	return a
 }
 
 func GetFileData(fnames []string, regex string, random bool, count int) ([]string, error) {
+	return defaultGenerator().GetFileData(fnames, regex, random, count)
+}
+
+func (g *Generator) GetFileData(fnames []string, regex string, random bool, count int) ([]string, error) {
 
 	sequential := !random
 
-	r := new(regexp.Regexp)
+	var r *regexp.Regexp
 	if regex != "" {
 		var err error
 		r, err = regexp.Compile(regex)
@@ -90,45 +93,25 @@ func GetFileData(fnames []string, regex string, random bool, count int) ([]strin
 		}
 	}
 
-	var bs [][]byte
-
-	//open file and read contents as an array
-	//combine multiple files
-	for _, fname := range fnames {
-		b, err := ioutil.ReadFile(fname)
-		if err != nil {
-			return nil, err
-		}
-		tmpBs := bytes.Split(b, []byte{'\n'})
-		if len(tmpBs) > 0 {
-			bs = append(bs, tmpBs...)
-		}
-	}
-
-	//see if regex is non empty
-	if regex != "" {
-		//else filter array 
-		for i := len(bs) - 1; i >= 0; i-- {
-			if !r.Match(bs[i]) {
-				bs = append(bs[:i], bs[i+1:]...)
-			}
-		}
+	lines, _, err := g.dictLines(fileSource(fnames), r)
+	if err != nil {
+		return nil, err
 	}
 
 	// if len(allLines) == 0 {
-	if len(bs) == 0 {
+	if len(lines) == 0 {
 		return nil, nil
 	}
 
 	//if sequential, then take first "count" items
 	var retLines []string
 	if sequential {
-		if count > len(bs) {
-			count = len(bs)
+		if count > len(lines) {
+			count = len(lines)
 		}
 
 		for i := 0; i < count; i++ {
-			retLines = append(retLines, string(bs[i]))
+			retLines = append(retLines, lines[i])
 		}
 		return retLines, nil
 	}
@@ -136,8 +119,8 @@ func GetFileData(fnames []string, regex string, random bool, count int) ([]strin
 	//if random, then loop for "count" items randomly
 	if random {
 		for i := 0; i < count; i++ {
-			pos := rand.Intn(len(bs))
-			retLines = append(retLines, string(bs[pos]))
+			pos := g.rand.Intn(len(lines))
+			retLines = append(retLines, lines[pos])
 		}
 		return retLines, nil
 	}
@@ -145,24 +128,6 @@ func GetFileData(fnames []string, regex string, random bool, count int) ([]strin
 	return nil, nil
 }
 
-func getOptionsMap(s string) map[string]string {
-	// func getOptionsMap(s string, in interface{}) error {
-	parts := strings.Split(s, "|")
-	mParts := make(map[string]string)
-	for _, onePart := range parts {
-		onePart = strings.TrimSpace(onePart)
-		subParts := strings.Split(onePart, ":")
-		key := strings.ToLower(strings.TrimSpace(subParts[0]))
-		val := ""
-		if len(subParts) > 1 { // part following : exists
-			val = strings.TrimSpace(subParts[1])
-		}
-		mParts[key] = val
-	}
-
-	return mParts
-}
-
 func setOptions(mParts map[string]string, in interface{}) error {
 
 	v := reflect.ValueOf(in).Elem()
@@ -176,6 +141,24 @@ func setOptions(mParts map[string]string, in interface{}) error {
 		for key, val := range mParts {
 			if strings.ToLower(fieldName) == key {
 				// fieldVal = val
+				//times and durations are checked before their underlying kinds
+				switch v.Field(i).Type() {
+				case timeType:
+					tmpTime, err := parseTime(val)
+					if err != nil {
+						return err
+					}
+					v.Field(i).Set(reflect.ValueOf(tmpTime))
+					continue
+				case durationType:
+					tmpDuration, err := parseDuration(val)
+					if err != nil {
+						return err
+					}
+					v.Field(i).SetInt(int64(tmpDuration))
+					continue
+				}
+
 				switch v.Field(i).Kind() {
 				case reflect.Int:
 					//convert fieldVal to int and then assign it
@@ -184,12 +167,22 @@ func setOptions(mParts map[string]string, in interface{}) error {
 						return err
 					}
 					v.Field(i).SetInt(tmpInt)
+				case reflect.Int64:
+					tmpInt, err := strconv.ParseInt(val, 0, 64)
+					if err != nil {
+						return err
+					}
+					v.Field(i).SetInt(tmpInt)
+				case reflect.Float64:
+					tmpFloat, err := strconv.ParseFloat(val, 64)
+					if err != nil {
+						return err
+					}
+					v.Field(i).SetFloat(tmpFloat)
 				case reflect.Bool:
 					v.Field(i).SetBool(true)
 				case reflect.String:
-					//if val contains quotes, remove it
-					val = strings.TrimLeft(val, "\"'")
-					val = strings.TrimRight(val, "\"'")
+					//quotes around val are already removed by the parser
 					//TODO: have to find a proper way to do \n type chars
 					v.Field(i).SetString(val)
 				}
@@ -200,86 +193,77 @@ func setOptions(mParts map[string]string, in interface{}) error {
 	return nil
 }
 
+//the bundled datasets.  These are compiled into the package, see builtinData.
 var mFiles = map[string][]string{
 	"country":   []string{"country.txt"},
 	"firstname": []string{"firstname.txt"},
 }
 
+type fileOptions struct {
+	Regex   string
+	Random  bool
+	Unique  bool //no value is given twice
+	Shuffle bool //the lines in a random order
+	Cycle   bool //start again from the first line instead of running out
+}
+
 func GenFileElement(fnames []string, mParts map[string]string, count int) ([]string, error) {
+	return defaultGenerator().GenFileElement(fnames, mParts, count)
+}
+
+func (g *Generator) GenFileElement(fnames []string, mParts map[string]string, count int) ([]string, error) {
 	// func City(s string) ([]string, error) {
 
-	opts := struct {
-		Regex  string
-		Random bool
-		// Count  int
-	}{
-		"",
-		false,
-		// 1,
+	//the files are read like a dictionary so that all its options work
+	el := &ElementNode{Name: "file"}
+	keys := make([]string, 0, len(mParts))
+	for key := range mParts {
+		keys = append(keys, key)
 	}
-
-	// err := getOptionsMap(s, &opts)
-	err := setOptions(mParts, &opts)
-	if err != nil {
-		return nil, err
+	sort.Strings(keys)
+	for _, key := range keys {
+		el.Options = append(el.Options, Option{Key: strings.ToLower(key), Value: mParts[key]})
 	}
 
-	return GetFileData(fnames, opts.Regex, opts.Random, count)
+	eg, ctx := g.contextFor(dictElement{fileSource(fnames)}, el, count)
+	return generate(eg, ctx, count)
 }
 
 // Generate string data for a single element
-// city/firstname | regex: | random 
+// city/firstname | regex: | random
 func GenElement(eb string, count int) ([]string, error) {
-	mOpts := getOptionsMap(eb)
-	for key, val := range mOpts {
-		if val == "" { //these won't have a val part.  TODO: Anyways, not foolproof.
-			switch key {
-			case "country":
-				// return City(mOpts, count)
-				return GenFileElement(mFiles["country"], mOpts, count)
-			case "firstname":
-				// return FirstName(mOpts, count)
-				return GenFileElement(mFiles["firstname"], mOpts, count)
-			default:
-				return nil, fmt.Errorf("Unknown element type: %s", key)
-			}
-		}
+	return defaultGenerator().GenElement(eb, count)
+}
+
+func (g *Generator) GenElement(eb string, count int) ([]string, error) {
+	g = g.begin()
+	opts, err := newParser(eb, DEFAULT).parseList(item{}, "", ErrUnbalancedElement)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	data, err := g.genElement(elementNode(opts[0].Pos, opts), count)
+	return data, locate(err, eb)
 }
 
 type blockOptions struct {
 	Count         int
+	Seed          int64
 	Separator     string
 	LastSeparator string
 	ElementBegin  string
 	ElementEnd    string
+	Escape        string //csv, json, xml, sql, html, auto or none
 }
 
 // Parses options string to give back options.  Input string includes the enclosing begin and end separators for the options block 
 func getBlockOptions(s string, mo MarkerOptions) (*blockOptions, error) {
-	//set defaults
-	// bo := blockOptions{
-	// 	Count:         1,
-	// 	Separator:     "\n",
-	// 	LastSeparator: "\n",
-	// 	ElementBegin:  "{{",
-	// 	ElementEnd:    "}}",
-	// }
-
-	// the default values are first set
-	bo := blockOptions{
-		Count:         1,
-		Separator:     mo.Separator,
-		LastSeparator: mo.LastSeparator,
-		ElementBegin:  mo.ElementBegin,
-		ElementEnd:    mo.ElementEnd,
-	}
-
-	// if any other options are specified, then the defaults are overridden
+	// if no options are specified, the defaults from the markers are used
 	s = strings.TrimSpace(s)
 	if len(s) == 0 {
-		return &bo, nil
+		return blockOptionsFrom(nil, mo)
 	}
 
 	if len(mo.OptionsBegin) > 0 {
@@ -288,16 +272,13 @@ func getBlockOptions(s string, mo MarkerOptions) (*blockOptions, error) {
 	if len(mo.OptionsEnd) > 0 {
 		s = strings.TrimSuffix(s, mo.OptionsEnd)
 	}
-	s = strings.TrimSpace(s)
-
-	mOpts := getOptionsMap(s)
 
-	//TODO: should add a report extra params as error option in setOptions
-	if err := setOptions(mOpts, &bo); err != nil {
+	opts, err := newParser(s, mo).parseList(item{}, "", ErrUnbalancedOptions)
+	if err != nil {
 		return nil, err
 	}
-
-	return &bo, nil
+	bo, err := blockOptionsFrom(&OptionsNode{0, opts}, mo)
+	return bo, locate(err, s)
 }
 
 type subBlock struct {
@@ -361,52 +342,45 @@ func getSubBlock(s string, mo.BlockBegin, mo.BlockEnd string) subBlock {
 */
 
 //return innermost block with BlockBegin and BlockEnd markers
-func getSubBlock(s string, mo MarkerOptions) subBlock {
+func getSubBlock(s string, mo MarkerOptions) (subBlock, error) {
 
 	//find the first occurrence of ending marker
 	end := strings.Index(s, mo.BlockEnd)
 	if end < 0 {
-		return subBlock{}
+		return subBlock{}, nil
 	}
 
 	//from the first end marker, search backwards to find the first beginning marker
 	partS := s[:end+len(mo.BlockEnd)]
 	begin := strings.LastIndex(partS, mo.BlockBegin)
 	if begin < 0 {
-		panic("getSubBlock: end marker found but no matching beginning marker.")
+		return subBlock{}, locate(newParseError(ErrUnbalancedBlock, Pos(end), mo.BlockEnd, "end marker found but no matching beginning marker"), s)
 	}
 
-	return subBlock{s[begin : end+len(mo.BlockEnd)], begin, end}
+	return subBlock{s[begin : end+len(mo.BlockEnd)], begin, end}, nil
 }
 
-func getSubBlockOuter(s string, beginMark, endMark string) subBlock {
+func getSubBlockOuter(s string, beginMark, endMark string) (subBlock, error) {
 
 	beginCtr := 0
 	begin := strings.Index(s, beginMark)
 	end := begin + len(beginMark)
 	if begin < 0 {
-		return subBlock{}
+		return subBlock{}, nil
 	} else {
 		beginCtr = beginCtr + 1
 		newS := s[begin+len(beginMark):]
 		for beginCtr != 0 {
-			// fmt.Println("newS: ", newS)
-			// fmt.Println("beginCtr: ", beginCtr)
-			// fmt.Println("begin: ", begin)
-			// fmt.Println("end: ", end)
 			aBegin := strings.Index(newS, beginMark)
 			aEnd := strings.Index(newS, endMark)
-			// fmt.Println("aBegin: ", aBegin)
-			// fmt.Println("aEnd: ", aEnd)
-			if aBegin == -1 && aEnd == -1 {
-				panic("No matching ending marker: " + endMark)
+			if aEnd == -1 {
+				return subBlock{}, locate(newParseError(ErrUnbalancedBlock, Pos(begin), beginMark, "no matching ending marker: %s", endMark), s)
 			}
-			if (aBegin == -1 && aEnd > 0) || (aEnd < aBegin) { //this is an innermost match
+			if aBegin == -1 || aEnd < aBegin { //this is an innermost match
 				beginCtr = beginCtr - 1
 				newS = newS[aEnd+len(endMark):]
 				end = end + aEnd + len(endMark)
-			}
-			if aEnd > aBegin && aBegin != -1 { //have to go a level deeper
+			} else { //have to go a level deeper
 				beginCtr = beginCtr + 1
 				newS = newS[aBegin+len(beginMark):]
 				end = end + aBegin + len(beginMark)
@@ -414,145 +388,82 @@ func getSubBlockOuter(s string, beginMark, endMark string) subBlock {
 		}
 	}
 
-	return subBlock{s[begin:end], begin, end}
+	return subBlock{s[begin:end], begin, end}, nil
 }
 
 //Generate data for a datagen block
-// func GenBlockX(s, mo.BlockBegin, mo.BlockEnd, mo.OptionsBegin, mo.OptionsEnd string) (string, error) {
 func GenBlockX(s string, mo MarkerOptions) (string, error) {
+	return defaultGenerator().GenBlockX(s, mo)
+}
 
-	fmt.Println("Given string: " + s)
-	s = strings.TrimSpace(s)
-	if len(s) == 0 {
+func (g *Generator) GenBlockX(s string, mo MarkerOptions) (string, error) {
+	g = g.begin()
+
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) == 0 {
 		return "", nil
 	}
 
 	//remove enclosing {{{ and }}}, or whatever is prefix and suffix of the entire block
-	if len(mo.BlockBegin) > 0 {
-		s = strings.TrimPrefix(s, mo.BlockBegin)
-	}
-	if len(mo.BlockEnd) > 0 {
-		s = strings.TrimSuffix(s, mo.BlockEnd)
-	}
-	s = strings.TrimSpace(s)
-	fmt.Println("Given string after trimming: " + s)
-
-	//check if there are further sub blocks
-	fmt.Println("begin s:" + s)
-	sub := getSubBlock(s, mo)
-	fmt.Println("subBlock:" + sub.block)
-	if sub.block != "" {
-		genSub, err := GenBlock(sub.block)
-		if err != nil {
-			return "", err
-		}
-		fmt.Println("genSub:" + genSub)
-
-		// dataS = dataS[:sub.start] + genSub + dataS[sub.end:]
-		// s = s[:sub.start] + genSub + s[sub.end+len(mo.BlockEnd):]
-		s = strings.TrimSpace(s[:sub.start]) + genSub + strings.TrimSpace(s[sub.end+len(mo.BlockEnd):])
-		fmt.Println("next s: " + s)
+	//positions are kept relative to s for error reporting
+	start := strings.Index(s, trimmed)
+	end := start + len(trimmed)
+	if len(mo.BlockBegin) > 0 && strings.HasPrefix(s[start:end], mo.BlockBegin) {
+		start += len(mo.BlockBegin)
 	}
-
-	fmt.Println("After recursive calls s:" + s)
-
-	optBeginPos := strings.Index(s, mo.OptionsBegin)
-	optEndPos := strings.Index(s, mo.OptionsEnd)
-	if optBeginPos >= 0 && optEndPos < 0 {
-		return "", fmt.Errorf("Beginning of options marker found (" + mo.OptionsBegin + ") but did not find end marker (" + mo.OptionsEnd + ").")
-	} else if optBeginPos < 0 && optEndPos >= 0 {
-		return "", fmt.Errorf("End of options marker found (" + mo.OptionsEnd + ") but did not find beginning marker (" + mo.OptionsBegin + ").")
-	} else if optBeginPos > optEndPos {
-		return "", fmt.Errorf("Position of beginning of options marker (" + mo.OptionsBegin + ") is after position of end of options marker (" + mo.OptionsEnd + ").")
+	if len(mo.BlockEnd) > 0 && strings.HasSuffix(s[start:end], mo.BlockEnd) {
+		end -= len(mo.BlockEnd)
 	}
 
-	optionsS := ""
-	if optEndPos > optBeginPos {
-		optionsS = strings.TrimSpace(s[optBeginPos+len(mo.OptionsBegin) : optEndPos])
-	}
-
-	bo, err := getBlockOptions(optionsS, mo)
+	b, err := parseBody(s, start, end, mo)
 	if err != nil {
 		return "", err
 	}
-
-	dataS := s
-	if optEndPos > 0 {
-
-		dataS = strings.TrimSpace(s[:optBeginPos] + s[optEndPos+len(mo.OptionsEnd):])
+	var sb strings.Builder
+	if err := g.evalBlock(&output{w: &sb}, b, mo, nil, nil, g.count); err != nil {
+		return "", locate(err, s)
 	}
-	fmt.Println("dataS begin:" + dataS)
-
-	// else read individual element parts
-	mElements := make(map[string]string)
-	markerCnt := 0
-	for {
-		elBeginPos := strings.Index(dataS, bo.ElementBegin)
-		elEndPos := strings.Index(dataS, bo.ElementEnd)
-		if elBeginPos < 0 || elEndPos < 0 {
-			break
-		}
+	return sb.String(), nil
+}
 
-		//assuming here that it is syntatically ok.  TODO: fix this.
+func GenBlock(s string) (string, error) {
+	// return GenBlockX(s, "{{{", "}}}", "[[[", "]]]")
+	return GenBlockX(s, DEFAULT)
+}
 
-		//replace element definition with a marker
-		nxtMarker := "<$" + strconv.FormatInt(int64(markerCnt), 10) + "$>"
-		elementS := dataS[elBeginPos+len(bo.ElementBegin) : elEndPos]
-		mElements[nxtMarker] = elementS
-		dataS = dataS[:elBeginPos] + nxtMarker + dataS[elEndPos+len(bo.ElementEnd):]
-		markerCnt = markerCnt + 1
-	}
+func (g *Generator) GenBlock(s string) (string, error) {
+	return g.GenBlockX(s, DEFAULT)
+}
 
-	mGenElements := make(map[string][]string)
-	// for each element, call GenElement with count 
-	for marker, elDef := range mElements {
-		data, err := GenElement(elDef, bo.Count)
-		if err != nil {
-			return "", err
-		}
-		mGenElements[marker] = data
-	}
-	// fmt.Printf("mGenElements: %+v \n", mGenElements)
-
-	//substitue data block with strings from GenElements
-	fullS := ""
-	for i := 0; i < bo.Count; i++ {
-		tmpS := dataS
-		// fmt.Printf("tmpS %+v \n", tmpS)
-		for marker, data := range mGenElements {
-			tmpS = strings.Replace(tmpS, marker, data[i], 1)
-		}
+//Generate data for an entire input.
+func Gen(s string, mo MarkerOptions) (string, error) {
+	return defaultGenerator().Gen(s, mo)
+}
 
-		if i == bo.Count-1 {
-			tmpS += bo.LastSeparator
-		} else {
-			tmpS += bo.Separator
-		}
-		fullS = fullS + tmpS
+func (g *Generator) Gen(s string, mo MarkerOptions) (string, error) {
+	var sb strings.Builder
+	if err := g.GenTo(&sb, s, mo); err != nil {
+		return "", err
 	}
-
-	fmt.Println("fullS: " + fullS)
-	return fullS, nil
+	return sb.String(), nil
 }
 
-func GenBlock(s string) (string, error) {
-	// return GenBlockX(s, "{{{", "}}}", "[[[", "]]]")
-	return GenBlockX(s, DEFAULT)
+// GenTo writes the data for an entire input to w as it is generated, a row at
+// a time, so that the memory used does not grow with the counts of blocks.
+// Data written before an error is not taken back.
+func GenTo(w io.Writer, s string, mo MarkerOptions) error {
+	return defaultGenerator().GenTo(w, s, mo)
 }
 
-//Generate data for an entire input.  To be called recursively.
-func Gen(s string, mo MarkerOptions) (string, error) {
-	for {
-		sub := getSubBlockOuter(s, mo.BlockBegin, mo.BlockEnd)
-		if sub.block == "" {
-			break
-		}
-		
-		gen, err := GenBlockX(sub.block, mo)
-		if err != nil {
-			return "", err
-		}
-		s = s[:sub.start] + gen + s[sub.end:]
+func (g *Generator) GenTo(w io.Writer, s string, mo MarkerOptions) error {
+	g = g.begin()
+	t, err := Parse(s, mo)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err := g.evalTemplate(&output{w: bw}, t); err != nil {
+		return locate(err, s)
 	}
-	return s, nil
+	return bw.Flush()
 }
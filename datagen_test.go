@@ -1,7 +1,10 @@
 package datagen
 
 import (
+	"errors"
+	"runtime"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -386,7 +389,11 @@ abcd
 efgh
 `
 
-	sub := getSubBlock(s, DEFAULT)
+	sub, err := getSubBlock(s, DEFAULT)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 
 	if sub.block != "{{{ 1234 }}}" {
 		t.Errorf("FAIL. Expected %+v. \nReceived %+v.", "{{{ 1234 }}}", sub.block)
@@ -401,7 +408,11 @@ abcd
 efgh
 `
 
-	sub = getSubBlock(s, DEFAULT)
+	sub, err = getSubBlock(s, DEFAULT)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if sub.block != "{{{ 1234 }}}" {
 		t.Errorf("FAIL. Expected %+v. \nReceived %+v.", "{{{ 1234 }}}", sub.block)
 	} else {
@@ -415,7 +426,11 @@ abcd
 efgh
 `
 
-	sub = getSubBlock(s, DEFAULT)
+	sub, err = getSubBlock(s, DEFAULT)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if sub.block != "{{{ 5678 }}}" {
 		t.Errorf("FAIL. Expected %+v. \nReceived %+v.", "{{{ 5678 }}}", sub.block)
 	} else {
@@ -428,7 +443,11 @@ abcd {{{ 1234 {{{5678 {{{ 9012 }}} }}} }}}
 {{{ a1234 {{{b5678 {{{ c9012 }}} }}} }}} efgh
 `
 
-	sub = getSubBlock(s, DEFAULT)
+	sub, err = getSubBlock(s, DEFAULT)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if sub.block != "{{{ 9012 }}}" {
 		t.Errorf("FAIL. Expected %+v. \nReceived %+v.", "{{{ 9012 }}}", sub.block)
 	} else {
@@ -449,7 +468,11 @@ abcd
 efgh
 `
 
-	sub = getSubBlock(s, DEFAULT)
+	sub, err = getSubBlock(s, DEFAULT)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if sub.block != "{{{ 90\n12 }}}" {
 		t.Errorf("FAIL. Expected %+v. \nReceived %+v.", "{{{ 90\n12 }}}", sub.block)
 	} else {
@@ -566,7 +589,11 @@ func Test_getSubBlockOuter(t *testing.T) {
 	exp := `{{ efgh {{ abcd}} }}`
 
 	// s, err := GenBlock(block, "{{{", "}}}", 
-	sub := getSubBlockOuter(block, "{{", "}}")
+	sub, err := getSubBlockOuter(block, "{{", "}}")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 
 	if sub.block != exp {
 		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, sub.block)
@@ -579,7 +606,11 @@ func Test_getSubBlockOuter(t *testing.T) {
 	exp = `{{ efgh {{ {{ijkl}} {{ mnop }} abcd}} }}`
 
 	// s, err := GenBlock(block, "{{{", "}}}", 
-	sub = getSubBlockOuter(block, "{{", "}}")
+	sub, err = getSubBlockOuter(block, "{{", "}}")
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 
 	if sub.block != exp {
 		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, sub.block)
@@ -607,3 +638,146 @@ func Test_Gen(t *testing.T) {
 		t.Logf("PASS. Expected %+v. Received %+v.", exp, gen)
 	}
 }
+
+func Test_GenBlock_Nested_Markers(t *testing.T) {
+	for _, tc := range []struct {
+		block string
+		mo    MarkerOptions
+		exp   string
+	}{
+		{
+			"{{ [count:2 | separator: ';'] {{ [count:3 | separator: '-' | lastseparator: ''] { country } }} : { firstname } }}",
+			CSV,
+			"Afghanistan-Albania-Algeria: AARON;Afghanistan-Albania-Algeria: ABDUL\n",
+		},
+		{
+			"{{ [[count:2]] <person><name>{ firstname }</name>{{ [[count:2 | separator: '' | lastseparator: '']] <country>{ country }</country> }}</person> }}",
+			XML,
+			"<person><name>AARON</name><country>Afghanistan</country><country>Albania</country></person>\n" +
+				"<person><name>ABDUL</name><country>Afghanistan</country><country>Albania</country></person>\n",
+		},
+		{
+			"$( $[count:2 | separator: ', ' | lastseparator: '.']$ ${ firstname }$ - $( $[count:2 | separator: '+' | lastseparator: '']$ ${ country | regex: ^B }$ )$ )$",
+			DOLLAR,
+			"AARON -Bahamas+Bahrain, ABDUL -Bahamas+Bahrain.",
+		},
+		{
+			"<% [count:2 | separator: '|' | lastseparator: ''] <@ firstname @>: <% [count:2 | separator: ',' | lastseparator: ''] <@ country | regex: ^C @> <% [count:2 | separator: '' | lastseparator: ''] . %> %> %>",
+			MarkerOptions{"<%", "%>", "[", "]", "<@", "@>", "\n", "\n"},
+			"AARON:Cambodia..,Cameroon..|ABDUL:Cambodia..,Cameroon..",
+		},
+	} {
+		s, err := GenBlockX(tc.block, tc.mo)
+		if err != nil {
+			t.Errorf("Unexpected error. %v", err)
+			continue
+		}
+
+		if s != tc.exp {
+			t.Errorf("FAIL. Expected %+v. Received %+v.", tc.exp, s)
+		} else {
+			t.Logf("PASS. Expected %+v. Received %+v.", tc.exp, s)
+		}
+	}
+}
+
+func Test_GenBlock_Nested_PerRow(t *testing.T) {
+	block := `{{{ [[[ count: 2 | separator: '|' | lastseparator: '' ]]] {{{ [[[ count: 3 | separator: ',' | lastseparator: '' ]]] {{ firstname | random }} }}} }}}`
+
+	s, err := GenBlock(block)
+	if err != nil {
+		t.Fatalf("Unexpected error. %v", err)
+	}
+
+	rows := strings.Split(s, "|")
+	if len(rows) != 2 || len(strings.Split(rows[0], ",")) != 3 {
+		t.Fatalf("FAIL. Expected 2 rows of 3 names. Received %+v.", s)
+	}
+
+	//the chances that both rows get the same random names are very very low. But not impossible.
+	if rows[0] == rows[1] {
+		t.Errorf("FAIL. Expected the sub block to be generated for each row. Received %+v.", s)
+	} else {
+		t.Logf("PASS. Received %+v.", s)
+	}
+}
+
+func Test_GenTo(t *testing.T) {
+	s := "id,name\n{{{ [[[ count: 3 | seed: 7 ]]] {{ seq }},{{ firstname | random }} }}}"
+
+	var sb strings.Builder
+	if err := NewGenerator(1).GenTo(&sb, s, DEFAULT); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp, err := NewGenerator(1).Gen(s, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sb.String() != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, sb.String())
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, sb.String())
+	}
+
+	//errors writing are returned
+	err = NewGenerator(1).GenTo(failingWriter{}, s, DEFAULT)
+	if !errors.Is(err, errWrite) {
+		t.Errorf("FAIL. Expected %v. Received %v.", errWrite, err)
+	}
+}
+
+var errWrite = errors.New("disk full")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+// heapWriter throws away what is written, but keeps the most heap in use,
+// checked every few MB.
+type heapWriter struct {
+	n, maxHeap uint64
+}
+
+func (w *heapWriter) Write(p []byte) (int, error) {
+	before := w.n
+	w.n += uint64(len(p))
+	if w.n>>22 != before>>22 {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		if ms.HeapAlloc > w.maxHeap {
+			w.maxHeap = ms.HeapAlloc
+		}
+	}
+	return len(p), nil
+}
+
+func Test_GenTo_FlatMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("10 million rows take a while")
+	}
+
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	start := ms.HeapAlloc
+
+	w := &heapWriter{}
+	s := "{{{ [[[ count: 10000000 | escape: csv ]]] {{ seq }},{{ firstname | random }},{{ int | min: 1 | max: 99 }} }}}"
+	if err := NewGenerator(1).GenTo(w, s, DEFAULT); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	//the output is over 100MB, but the heap stays small
+	const limit = 32 << 20
+	growth := uint64(0)
+	if w.maxHeap > start {
+		growth = w.maxHeap - start
+	}
+	if w.n < 100<<20 || growth > limit {
+		t.Errorf("FAIL. Expected the heap to grow by less than %d MB. Received %d MB for %d MB of output.", limit>>20, growth>>20, w.n>>20)
+	} else {
+		t.Logf("PASS. Heap grew by at most %d MB for %d MB of output.", growth>>20, w.n>>20)
+	}
+}
@@ -0,0 +1,195 @@
+package datagen
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// timeLayouts are tried in turn for times given as options.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime parses a date or time given as an option.  Times without a zone
+// are taken as UTC.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can not parse %q as a date or time", s)
+}
+
+// parseDuration is time.ParseDuration which also takes days, e.g. 2d.
+func parseDuration(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("can not parse %q as a duration", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// timeFormats are the names which can be given to the format option.
+var timeFormats = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"ansic":       time.ANSIC,
+	"kitchen":     time.Kitchen,
+	"date":        "2006-01-02",
+	"datetime":    "2006-01-02 15:04:05",
+	"time":        "15:04:05",
+
+	//these are numbers rather than layouts
+	"unix":      "",
+	"unixmilli": "",
+	"unixnano":  "",
+}
+
+// timeOptions are the options of the date, time and timestamp elements, e.g.
+// {{ date | from: 2020-01-01 | to: 2024-12-31 | layout: "02/01/2006" }} or
+// {{ timestamp | from: 2024-01-01 | step: 1m | jitter: 10s | format: unix }}
+type timeOptions struct {
+	From, To time.Time
+	Layout   string        //a Go time layout
+	Format   string        //one of timeFormats, if no layout is given
+	TZ       string        //the location the times are shown in
+	Step     time.Duration //gives a series of increasing times from From
+	Jitter   time.Duration //a random addition of up to Jitter to each time of a series
+}
+
+// timeElement gives random times between two times, or a series of times.
+// Dates are whole days of the calendar, which tz does not change.
+type timeElement struct {
+	format string
+	dates  bool
+}
+
+type timeState struct {
+	opts timeOptions
+	loc  *time.Location
+}
+
+func (te timeElement) Generate(ctx *Context) (string, error) {
+	st, ok := ctx.State.(*timeState)
+	if !ok {
+		var err error
+		if st, err = te.prepare(ctx); err != nil {
+			return "", err
+		}
+		ctx.State = st
+	}
+
+	o := st.opts
+	var t time.Time
+	switch {
+	case o.Step > 0:
+		t = o.From.Add(time.Duration(ctx.Row) * o.Step)
+		if o.Jitter > 0 {
+			t = t.Add(time.Duration(ctx.Rand.Int63n(int64(o.Jitter))))
+		}
+	case te.dates:
+		//Sub is limited to about 292 years, so the span is from Unix
+		days := (o.To.Unix() - o.From.Unix()) / (24 * 60 * 60)
+		t = o.From.AddDate(0, 0, int(ctx.Rand.Int63n(days+1)))
+	default:
+		secs := o.To.Unix() - o.From.Unix()
+		if o.To.Nanosecond() < o.From.Nanosecond() {
+			secs--
+		}
+		t = time.Unix(o.From.Unix()+ctx.Rand.Int63n(secs+1), int64(o.From.Nanosecond()))
+	}
+	//dates are days of the calendar, which a zone would move
+	if !te.dates {
+		t = t.In(st.loc)
+	}
+
+	switch o.Format {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unixmilli":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10), nil
+	case "unixnano":
+		return strconv.FormatInt(t.UnixNano(), 10), nil
+	}
+	return t.Format(o.Layout), nil
+}
+
+// calendarDay gives the start of the day of t, in its own location, as a
+// time in UTC where days are always 24 hours.
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (te timeElement) prepare(ctx *Context) (*timeState, error) {
+	st := &timeState{opts: timeOptions{
+		From:   time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Format: te.format,
+		TZ:     "UTC",
+	}}
+	if err := ctx.Decode(&st.opts); err != nil {
+		return nil, err
+	}
+
+	o := &st.opts
+	if o.To.Before(o.From) {
+		return nil, ctx.OptionError("to", "to %v is before from %v", o.To, o.From)
+	}
+	if te.dates {
+		o.From = calendarDay(o.From)
+		o.To = calendarDay(o.To)
+	}
+	if o.Step < 0 {
+		return nil, ctx.OptionError("step", "step can not be negative")
+	}
+	//jitter less than the step keeps a series increasing
+	if o.Jitter < 0 || (o.Step > 0 && o.Jitter >= o.Step) {
+		return nil, ctx.OptionError("jitter", "jitter must be at least 0 and less than the step")
+	}
+
+	var err error
+	if st.loc, err = time.LoadLocation(o.TZ); err != nil {
+		return nil, ctx.OptionError("tz", "%v", err)
+	}
+
+	o.Format = strings.ToLower(o.Format)
+	if o.Layout == "" {
+		layout, ok := timeFormats[o.Format]
+		if !ok {
+			return nil, ctx.OptionError("format", "unknown format %q", o.Format)
+		}
+		o.Layout = layout
+	} else {
+		o.Format = ""
+	}
+	return st, nil
+}
+
+func (timeElement) OptionNames() []string {
+	return optionNames(timeOptions{})
+}
+
+func init() {
+	Register("date", timeElement{format: "date", dates: true})
+	Register("time", timeElement{format: "time"})
+	Register("timestamp", timeElement{format: "rfc3339"})
+}
@@ -0,0 +1,158 @@
+package datagen
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DateElement(t *testing.T) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	s, err := NewGenerator(1).GenElement(`date | from: 2020-01-01 | to: 2020-01-31 | layout: "02/01/2006"`, 50)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, v := range s {
+		d, err := time.Parse("02/01/2006", v)
+		if err != nil || d.Before(from) || d.After(to) {
+			t.Errorf("FAIL. Expected a date in January 2020. Received %s.", v)
+		}
+	}
+
+	s, err = NewGenerator(1).GenElement("date", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, v := range s {
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			t.Errorf("FAIL. Expected a date by default. Received %s.", v)
+		}
+	}
+}
+
+// dates stay within from and to whatever the zone
+func Test_DateElement_TZ(t *testing.T) {
+	for _, eb := range []string{
+		"date | from: 2020-01-01 | to: 2020-01-01 | tz: America/New_York",
+		"date | from: 2020-01-01 | to: 2020-01-01 | tz: Pacific/Kiritimati",
+		"date | from: 2020-01-01 | to: 2020-01-01 | tz: America/Los_Angeles | step: 24h",
+	} {
+		s, err := NewGenerator(1).GenElement(eb, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if s[0] != "2020-01-01" {
+			t.Errorf("FAIL. Expected 2020-01-01 for %s. Received %s.", eb, s[0])
+		} else {
+			t.Logf("PASS. Expected 2020-01-01 for %s. Received %s.", eb, s[0])
+		}
+	}
+}
+
+// ranges longer than time.Duration can hold are used in full
+func Test_TimeElement_WideRange(t *testing.T) {
+	for _, tc := range []struct {
+		eb     string
+		layout string
+	}{
+		{"date | from: 0001-01-01 | to: 9999-12-31", "2006-01-02"},
+		{"timestamp | from: 0001-01-01 | to: 9999-12-31T23:59:59Z | tz: UTC", time.RFC3339},
+	} {
+		s, err := NewGenerator(1).GenElement(tc.eb, 100)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		latest := 0
+		for _, v := range s {
+			if d, err := time.Parse(tc.layout, v); err == nil && d.Year() > latest {
+				latest = d.Year()
+			}
+		}
+		if latest < 5000 {
+			t.Errorf("FAIL. Expected years up to 9999 for %s. Received up to %d.", tc.eb, latest)
+		} else {
+			t.Logf("PASS. Expected years up to 9999 for %s. Received up to %d.", tc.eb, latest)
+		}
+	}
+}
+
+func Test_TimestampElement(t *testing.T) {
+	for _, tc := range []struct {
+		eb    string
+		parse func(string) error
+	}{
+		{"timestamp", func(s string) error { _, err := time.Parse(time.RFC3339, s); return err }},
+		{"timestamp | tz: UTC | format: rfc3339", func(s string) error {
+			if !strings.HasSuffix(s, "Z") {
+				return errors.New("not UTC")
+			}
+			_, err := time.Parse(time.RFC3339, s)
+			return err
+		}},
+		{"timestamp | format: unix", func(s string) error { _, err := strconv.ParseInt(s, 10, 64); return err }},
+		{"timestamp | format: kitchen", func(s string) error { _, err := time.Parse(time.Kitchen, s); return err }},
+		{"time", func(s string) error { _, err := time.Parse("15:04:05", s); return err }},
+		{"timestamp | tz: Asia/Kolkata", func(s string) error {
+			if !strings.HasSuffix(s, "+05:30") {
+				return errors.New("not in Asia/Kolkata")
+			}
+			return nil
+		}},
+	} {
+		s, err := NewGenerator(1).GenElement(tc.eb, 5)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		for _, v := range s {
+			if err := tc.parse(v); err != nil {
+				t.Errorf("FAIL. Unexpected value %s for %s: %v", v, tc.eb, err)
+				break
+			}
+		}
+		t.Logf("PASS. Received %v for %s.", s, tc.eb)
+	}
+}
+
+func Test_TimestampElement_Series(t *testing.T) {
+	s, err := NewGenerator(1).GenElement("timestamp | from: 2024-03-01T09:00:00Z | step: 1m | format: time", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "09:00:00,09:01:00,09:02:00"
+	if strings.Join(s, ",") != exp {
+		t.Errorf("FAIL. Expected %s. Received %v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %s. Received %v.", exp, s)
+	}
+
+	//with jitter the series still increases
+	s, err = NewGenerator(1).GenElement("timestamp | from: 2024-03-01 | step: 1d | jitter: 12h | format: unix", 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	prev := int64(0)
+	for _, v := range s {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		if n <= prev {
+			t.Errorf("FAIL. Expected increasing times. Received %v.", s)
+			break
+		}
+		prev = n
+	}
+}
+
+func Test_TimeElement_BadOptions(t *testing.T) {
+	expectBadOption(t,
+		"date | from: yesterday",
+		"date | from: 2024-01-01 | to: 2023-01-01",
+		"timestamp | step: 1m | jitter: 1m",
+		"timestamp | step: soon",
+		"timestamp | tz: Mars/Olympus",
+		"timestamp | format: stardate",
+	)
+}
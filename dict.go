@@ -0,0 +1,507 @@
+package datagen
+
+import (
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lineSource gives the lines of a dictionary.
+type lineSource interface {
+	lines() ([]string, error)
+}
+
+// splitLines splits data into lines.  A newline at the very end does not give
+// an extra empty line.
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// fileSource is a list of files read from disk.
+type fileSource []string
+
+func (fnames fileSource) lines() ([]string, error) {
+	var lines []string
+	//combine multiple files
+	for _, fname := range fnames {
+		b, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, splitLines(b)...)
+	}
+	return lines, nil
+}
+
+// fsSource is a list of files within a file system such as an embed.FS.  It
+// is used by pointer, so that the cache can know it.
+type fsSource struct {
+	fsys   fs.FS
+	fnames []string
+}
+
+func (s fsSource) lines() ([]string, error) {
+	var lines []string
+	for _, fname := range s.fnames {
+		b, err := fs.ReadFile(s.fsys, fname)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, splitLines(b)...)
+	}
+	return lines, nil
+}
+
+// linesSource is a dictionary held in memory.  It is used by pointer, so
+// that the cache can know it.
+type linesSource struct {
+	values []string
+}
+
+// newLinesSource keeps a copy of lines, so that changes to them later do not
+// go unseen by the cache.
+func newLinesSource(lines []string) *linesSource {
+	return &linesSource{append([]string(nil), lines...)}
+}
+
+func (ls *linesSource) lines() ([]string, error) {
+	return ls.values, nil
+}
+
+// dictElement gives the lines of a dictionary, in order or at random.  The
+// lines can be filtered with a regex.  Lines can end with a tab and a weight,
+// e.g. "Canada\t30", which makes random picks of them more or less likely.
+// In order, weighted lines are given in turn as often as their weights, as
+// by choice, and run out after one round of the total of the weights.
+//
+// With unique no line is given twice, random picks are made without
+// replacement, and fewer lines than the count is an error.  Shuffle gives
+// the lines in a random order, and cycle starts again from the first line
+// instead of running out.  Unique and shuffle give each line once, whatever
+// its weight.
+type dictElement struct {
+	src lineSource
+}
+
+func (dictElement) OptionNames() []string {
+	return optionNames(fileOptions{})
+}
+
+type dictState struct {
+	opts  fileOptions
+	lines []string
+	w     *weighted //nil if no line has a weight
+	order []int     //the order of the lines for shuffle and unique random picks
+
+	//weighted lines in order
+	current []float64
+	round   int
+}
+
+func (de dictElement) Generate(ctx *Context) (string, error) {
+	st, ok := ctx.State.(*dictState)
+	if !ok {
+		var err error
+		if st, err = de.prepare(ctx); err != nil {
+			return "", err
+		}
+		ctx.State = st
+	}
+
+	if len(st.lines) == 0 {
+		return "", ctx.exhaustedError("regex", "element %s has no lines to choose from", ctx.Name)
+	}
+	if st.opts.Random && st.order == nil {
+		if st.w != nil {
+			return st.w.random(ctx.Rand), nil
+		}
+		return st.lines[ctx.Rand.Intn(len(st.lines))], nil
+	}
+
+	n := len(st.lines)
+	if st.order != nil {
+		n = len(st.order)
+	}
+	if st.current != nil {
+		n = st.round
+	}
+	i := ctx.Row
+	if st.opts.Cycle {
+		i %= n
+	} else if i >= n {
+		if st.opts.Unique {
+			return "", ctx.exhaustedError("unique", "element %s has only %d unique values, but count is %d", ctx.Name, n, ctx.Count)
+		}
+		return "", ctx.exhaustedError("cycle", "element %s has only %d values, but count is %d; use cycle to start again from the first", ctx.Name, n, ctx.Count)
+	}
+	if st.current != nil {
+		return st.w.next(st.current), nil
+	}
+	if st.order != nil {
+		i = st.order[i]
+	}
+	return st.lines[i], nil
+}
+
+func (de dictElement) prepare(ctx *Context) (*dictState, error) {
+	st := &dictState{}
+	if err := ctx.Decode(&st.opts); err != nil {
+		return nil, err
+	}
+	o := st.opts
+	if o.Unique && o.Cycle {
+		return nil, ctx.OptionError("cycle", "cycle can not be used with unique")
+	}
+
+	var r *regexp.Regexp
+	if o.Regex != "" {
+		var err error
+		if r, err = regexp.Compile(o.Regex); err != nil {
+			return nil, ctx.OptionError("regex", "%v", err)
+		}
+	}
+	var err error
+	if st.lines, st.w, err = ctx.Generator.dictLines(de.src, r); err != nil {
+		return nil, err
+	}
+	if o.Unique {
+		st.lines, st.w = uniqueLines(st.lines, st.w)
+	}
+
+	switch {
+	case o.Unique && o.Random:
+		st.order = sampleOrder(ctx.Rand, st.lines, st.w)
+	case o.Shuffle:
+		st.order = ctx.Rand.Perm(len(st.lines))
+	case st.w != nil && !o.Unique && !o.Random:
+		st.current = make([]float64, len(st.lines))
+		if st.round = int(math.Round(st.w.total())); st.round < 1 {
+			st.round = 1
+		}
+	}
+	//a pool which is too small is reported before any data is given
+	if n := len(st.lines); o.Unique {
+		if st.order != nil {
+			n = len(st.order)
+		}
+		if ctx.Count > n {
+			return nil, ctx.OptionError("unique", "element %s has only %d unique values, but count is %d", ctx.Name, n, ctx.Count)
+		}
+	}
+	return st, nil
+}
+
+// sampleOrder gives the order of random picks without replacement.  Heavier
+// lines tend to come first, and lines with a weight of 0 are left out.
+func sampleOrder(r *rand.Rand, lines []string, w *weighted) []int {
+	if w == nil {
+		return r.Perm(len(lines))
+	}
+	//each line gets a key of u^(1/weight) and the largest keys are picked first
+	var order []int
+	keys := make([]float64, len(lines))
+	for i, wt := range w.weights {
+		if wt > 0 {
+			keys[i] = math.Pow(r.Float64(), 1/wt)
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]] > keys[order[b]] })
+	return order
+}
+
+// weightedLines splits the weights off the lines and keeps those which match
+// r and do not have a weight of 0.  The weights are only returned if a line
+// has one.
+func weightedLines(lines []string, r *regexp.Regexp) ([]string, *weighted) {
+	var values []string
+	var weights []float64
+	hasWeights := false
+	for _, line := range lines {
+		v, wt, ok := splitWeight(line)
+		hasWeights = hasWeights || ok
+		//lines with a weight of 0 are never given
+		if r != nil && !r.MatchString(v) || wt == 0 {
+			continue
+		}
+		values = append(values, v)
+		weights = append(weights, wt)
+	}
+	if !hasWeights {
+		return values, nil
+	}
+	return values, newWeighted(values, weights)
+}
+
+// uniqueLines keeps only the first of the same values, with its weight.
+func uniqueLines(values []string, w *weighted) ([]string, *weighted) {
+	var out []string
+	var weights []float64
+	seen := make(map[string]bool, len(values))
+	for i, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+		if w != nil {
+			weights = append(weights, w.weights[i])
+		}
+	}
+	if w == nil {
+		return out, nil
+	}
+	return out, newWeighted(out, weights)
+}
+
+// dictKey is a dictionary in the cache: its files, or its source if it is
+// not files on disk, and the regex its lines are filtered with.
+type dictKey struct {
+	src    lineSource
+	fnames string
+	regex  string
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type dictEntry struct {
+	stamps []fileStamp
+	values []string
+	w      *weighted
+}
+
+// dictCache keeps the filtered lines of dictionaries, so that each one is
+// read once rather than every time an element is used.  A file on disk is
+// read again when its modification time or size changes; other sources can
+// not change.
+type dictCache struct {
+	sync.Mutex
+	entries map[dictKey]*dictEntry
+}
+
+func newDictCache() *dictCache {
+	return &dictCache{entries: make(map[dictKey]*dictEntry)}
+}
+
+func (c *dictCache) lines(fnames fileSource, r *regexp.Regexp) ([]string, *weighted, error) {
+	stamps := make([]fileStamp, len(fnames))
+	for i, fname := range fnames {
+		fi, err := os.Stat(fname)
+		if err != nil {
+			return nil, nil, err
+		}
+		stamps[i] = fileStamp{fi.ModTime(), fi.Size()}
+	}
+
+	key := dictKey{fnames: strings.Join(fnames, "\x00")}
+	if r != nil {
+		key.regex = r.String()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok && sameStamps(e.stamps, stamps) {
+		return e.values, e.w, nil
+	}
+	lines, err := fnames.lines()
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &dictEntry{stamps: stamps}
+	e.values, e.w = weightedLines(lines, r)
+	c.entries[key] = e
+	return e.values, e.w, nil
+}
+
+// source gives the lines of a source other than files on disk
+func (c *dictCache) source(src lineSource, r *regexp.Regexp) ([]string, *weighted, error) {
+	key := dictKey{src: src}
+	if r != nil {
+		key.regex = r.String()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok {
+		return e.values, e.w, nil
+	}
+	lines, err := src.lines()
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &dictEntry{}
+	e.values, e.w = weightedLines(lines, r)
+	c.entries[key] = e
+	return e.values, e.w, nil
+}
+
+func sameStamps(a, b []fileStamp) bool {
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+// dictLines gives the values of a dictionary which match r, from the cache.
+func (g *Generator) dictLines(src lineSource, r *regexp.Regexp) ([]string, *weighted, error) {
+	if g.dicts != nil {
+		switch src := src.(type) {
+		case fileSource:
+			return g.dicts.lines(src, r)
+		case *fsSource, *linesSource:
+			return g.dicts.source(src, r)
+		}
+	}
+	lines, err := src.lines()
+	if err != nil {
+		return nil, nil, err
+	}
+	values, w := weightedLines(lines, r)
+	return values, w, nil
+}
+
+// RegisterDictionary registers an element which gives the lines of the files,
+// like the built in country element.  Relative paths are relative to the
+// current directory.
+func RegisterDictionary(name string, fnames ...string) {
+	Register(name, dictElement{fileSource(fnames)})
+}
+
+// RegisterDictionaryFS registers an element which gives the lines of the
+// files within fsys, e.g. an embed.FS.
+func RegisterDictionaryFS(name string, fsys fs.FS, fnames ...string) {
+	Register(name, dictElement{&fsSource{fsys, fnames}})
+}
+
+// RegisterDictionaryReader registers an element which gives the lines read
+// from r.  r is read fully before returning.
+func RegisterDictionaryReader(name string, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	Register(name, dictElement{&linesSource{splitLines(b)}})
+	return nil
+}
+
+// RegisterDictionaryLines registers an element which gives the lines.
+func RegisterDictionaryLines(name string, lines []string) {
+	Register(name, dictElement{newLinesSource(lines)})
+}
+
+// RegisterDictionary is like the package level RegisterDictionary, but only
+// for this generator.
+func (g *Generator) RegisterDictionary(name string, fnames ...string) {
+	g.Register(name, dictElement{fileSource(fnames)})
+}
+
+// RegisterDictionaryFS is like the package level RegisterDictionaryFS, but
+// only for this generator.
+func (g *Generator) RegisterDictionaryFS(name string, fsys fs.FS, fnames ...string) {
+	g.Register(name, dictElement{&fsSource{fsys, fnames}})
+}
+
+// RegisterDictionaryReader is like the package level
+// RegisterDictionaryReader, but only for this generator.
+func (g *Generator) RegisterDictionaryReader(name string, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	g.Register(name, dictElement{&linesSource{splitLines(b)}})
+	return nil
+}
+
+// RegisterDictionaryLines is like the package level RegisterDictionaryLines,
+// but only for this generator.
+func (g *Generator) RegisterDictionaryLines(name string, lines []string) {
+	g.Register(name, dictElement{newLinesSource(lines)})
+}
+
+// resolve gives the path of a file named in a template.  Relative paths are
+// relative to the directory of the template file, if there is one.
+func (g *Generator) resolve(fname string) string {
+	if filepath.IsAbs(fname) || g.dir == "" {
+		return fname
+	}
+	return filepath.Join(g.dir, fname)
+}
+
+// registerDictionaries handles the "dictionary: name: file1: file2" options of
+// a block.  The dictionaries are only available within the block, so a copy
+// of the generator is returned if there are any.
+func (g *Generator) registerDictionaries(opts []Option) (*Generator, error) {
+	scoped := false
+	for _, o := range opts {
+		if o.Key != "dictionary" {
+			continue
+		}
+		if len(o.Args) < 2 || o.Args[0] == "" {
+			return nil, newParseError(ErrBadOptionValue, o.Pos, o.Key, "expected dictionary: name: file")
+		}
+
+		var fnames []string
+		for _, fname := range o.Args[1:] {
+			fname = g.resolve(fname)
+			if _, err := os.Stat(fname); err != nil {
+				return nil, newParseError(ErrBadOptionValue, o.Pos, o.Key, "%v", err)
+			}
+			fnames = append(fnames, fname)
+		}
+
+		if !scoped {
+			g = g.withRegistry()
+			scoped = true
+		}
+		g.RegisterDictionary(o.Args[0], fnames...)
+	}
+	return g, nil
+}
+
+// GenFile generates data for the template in the file.  Dictionaries named
+// in the template are found relative to the file.
+func GenFile(fname string, mo MarkerOptions) (string, error) {
+	return defaultGenerator().GenFile(fname, mo)
+}
+
+func (g *Generator) GenFile(fname string, mo MarkerOptions) (string, error) {
+	var sb strings.Builder
+	if err := g.GenFileTo(&sb, fname, mo); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// GenFileTo is like GenFile, but writes the data to w as it is generated, as
+// GenTo does.
+func GenFileTo(w io.Writer, fname string, mo MarkerOptions) error {
+	return defaultGenerator().GenFileTo(w, fname, mo)
+}
+
+func (g *Generator) GenFileTo(w io.Writer, fname string, mo MarkerOptions) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	c := *g
+	c.dir = filepath.Dir(fname)
+	return c.GenTo(w, string(b), mo)
+}
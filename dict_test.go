@@ -0,0 +1,374 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test_RegisterDictionary_Sources(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("color", []string{"red", "green", "blue"})
+	if err := g.RegisterDictionaryReader("size", strings.NewReader("small\nmedium\nlarge\n")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g.RegisterDictionaryFS("fruit", fstest.MapFS{
+		"data/fruit.txt": {Data: []byte("apple\nbanana\ncherry")},
+	}, "data/fruit.txt")
+	g.RegisterDictionary("place", "test_data_file.txt")
+
+	for _, tc := range []struct {
+		eb  string
+		exp []string
+	}{
+		{"color", []string{"red", "green", "blue"}},
+		{"size | regex: ^[ml]", []string{"medium", "large"}},
+		{"fruit", []string{"apple", "banana", "cherry"}},
+		{"place | regex: ^Ba", []string{"Bahamas", "Bahrain", "Bangladesh", "Barbados"}},
+	} {
+		s, err := g.GenElement(tc.eb, len(tc.exp))
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		if strings.Join(s, ",") != strings.Join(tc.exp, ",") {
+			t.Errorf("FAIL. Expected %v for %s. Received %v.", tc.exp, tc.eb, s)
+		} else {
+			t.Logf("PASS. Expected %v for %s. Received %v.", tc.exp, tc.eb, s)
+		}
+	}
+
+	s, err := g.GenElement("color | random", 20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, c := range s {
+		if c != "red" && c != "green" && c != "blue" {
+			t.Errorf("FAIL. Expected only colors. Received %v.", s)
+			break
+		}
+	}
+}
+
+func Test_Dictionary_Directive(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "products.txt"), []byte("Chair\nTable\nLamp\nCouch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl := filepath.Join(dir, "products.tmpl")
+	s := "{{{ [[[ count: 3 | separator: ',' | dictionary: product: products.txt ]]] {{ product | regex: ^[CL] }} }}}"
+	if err := ioutil.WriteFile(tmpl, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//products.txt is found next to the template, not in the current directory
+	gen, err := NewGenerator(1).GenFile(tmpl, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "Chair,Lamp,Couch\n"
+	if gen != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, gen)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, gen)
+	}
+
+	//without a template file the path is relative to the current directory
+	_, err = NewGenerator(1).Gen(s, DEFAULT)
+	if !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v for a missing file. Received %v.", ErrBadOptionValue, err)
+	}
+
+	//the dictionary is only known within the block
+	_, err = NewGenerator(1).Gen("{{{ [[[ dictionary: place: test_data_file.txt ]]] {{ place }} }}} {{{ {{ place }} }}}", DEFAULT)
+	var pe *ParseError
+	if !errors.As(err, &pe) || !errors.Is(err, ErrUnknownElement) || pe.Col != 71 {
+		t.Errorf("FAIL. Expected %v at column 71. Received %v.", ErrUnknownElement, err)
+	}
+}
+
+func Test_Builtin_OtherDirectory(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	s, err := Gen("{{{ [[[ count: 2 | separator: ',' ]]] {{ country }} {{ firstname }} }}}", DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "Afghanistan AARON,Albania ABDUL\n"
+	if s != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, s)
+	}
+}
+
+func Test_Builtin_Override(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "country.txt")
+	if err := ioutil.WriteFile(fname, []byte("Atlantis\nLemuria\nZerzura\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGenerator(1)
+	g.RegisterDictionary("country", fname)
+	s, err := g.GenElement("country | regex: ^[AZ]", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(s, ",") != "Atlantis,Zerzura" {
+		t.Errorf("FAIL. Expected Atlantis,Zerzura. Received %v.", s)
+	}
+}
+
+func Test_Dictionary_Unique(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("pet", []string{"cat", "dog", "cat", "fish", "dog", "newt"})
+
+	//sequential unique drops the repeated lines
+	s, err := g.GenElement("pet | unique", 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := "cat,dog,fish,newt"; strings.Join(s, ",") != exp {
+		t.Errorf("FAIL. Expected %s. Received %v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %s. Received %v.", exp, s)
+	}
+
+	s, err = g.GenElement("pet | random | unique", 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, v := range s {
+		seen[v] = true
+	}
+	if len(s) != 4 || len(seen) != 4 {
+		t.Errorf("FAIL. Expected 4 different pets. Received %v.", s)
+	}
+
+	//the pool is too small, and cycle is not suggested as it can not be
+	//used with unique
+	for _, eb := range []string{"pet | random | unique", "pet | unique", "pet | shuffle | unique"} {
+		_, err = g.GenElement(eb, 5)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Kind != ErrBadOptionValue || !strings.Contains(err.Error(), "only 4 unique values") || strings.Contains(err.Error(), "cycle") {
+			t.Errorf("FAIL. Expected an error for too few values of %s. Received %v.", eb, err)
+		} else {
+			t.Logf("PASS. Received %v.", err)
+		}
+	}
+	_, err = g.Gen("{{{ [[[ count: 30 ]]] {{ country | random | unique }} }}} {{{ [[[ count: 3 ]]] {{ country | regex: ^Z | random | unique }} }}}", DEFAULT)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Col != 114 {
+		t.Errorf("FAIL. Expected an error at column 114. Received %v.", err)
+	}
+
+	//weights of 0 are never picked
+	g.RegisterDictionaryLines("coin", []string{"heads\t1", "tails\t1", "edge\t0"})
+	if _, err = g.GenElement("coin | random | unique", 3); !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v for weight 0. Received %v.", ErrBadOptionValue, err)
+	}
+}
+
+func Test_Dictionary_ShuffleCycle(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("color", []string{"red", "green", "blue"})
+
+	s, err := g.GenElement("color | cycle", 7)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := "red,green,blue,red,green,blue,red"; strings.Join(s, ",") != exp {
+		t.Errorf("FAIL. Expected %s. Received %v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %s. Received %v.", exp, s)
+	}
+
+	s, err = g.GenElement("color | shuffle", 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+	if strings.Join(sorted, ",") != "blue,green,red" {
+		t.Errorf("FAIL. Expected each color once. Received %v.", s)
+	}
+
+	//a shuffled cycle repeats the same order
+	s, err = g.GenElement("color | shuffle | cycle", 6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(s[:3], ",") != strings.Join(s[3:], ",") {
+		t.Errorf("FAIL. Expected the order to repeat. Received %v.", s)
+	}
+
+	if _, err = g.GenElement("color | cycle | unique", 2); !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v for cycle with unique. Received %v.", ErrBadOptionValue, err)
+	}
+
+	//in a block the dictionary running out is an error at the element
+	_, err = g.Gen("{{{ [[[ count: 4 ]]] {{ color }} }}}", DEFAULT)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Kind != ErrBadOptionValue || pe.Col != 22 || !strings.Contains(pe.Msg, "use cycle") {
+		t.Errorf("FAIL. Expected %v at column 22 suggesting cycle. Received %v.", ErrBadOptionValue, err)
+	} else {
+		t.Logf("PASS. Expected %v at column 22 suggesting cycle. Received %v.", ErrBadOptionValue, err)
+	}
+}
+
+func Test_GenFileElement_Options(t *testing.T) {
+	s, err := NewGenerator(1).GenFileElement([]string{"test_data_file.txt"}, map[string]string{"regex": "^Ba", "cycle": ""}, 6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "Bahamas,Bahrain,Bangladesh,Barbados,Bahamas,Bahrain"
+	if strings.Join(s, ",") != exp {
+		t.Errorf("FAIL. Expected %s. Received %v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %s. Received %v.", exp, s)
+	}
+}
+
+func Test_DictCache_Reload(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "team.txt")
+	if err := ioutil.WriteFile(fname, []byte("Ada\nAlan\nGrace\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGenerator(1)
+	g.RegisterDictionary("team", fname)
+	s, err := g.Gen("{{{ [[[ count: 2 | separator: ',' ]]] {{ team | regex: ^A }} {{{ [[[ lastseparator: '' ]]] {{ team }} }}} }}}", DEFAULT)
+	if exp := "AdaAda,AlanAda\n"; err != nil || s != exp {
+		t.Errorf("FAIL. Expected %q. Received %q, %v.", exp, s, err)
+	}
+	//one entry for each regex, however often the sub block is evaluated
+	if len(g.dicts.entries) != 2 {
+		t.Errorf("FAIL. Expected 2 cached dictionaries. Received %d.", len(g.dicts.entries))
+	}
+
+	//a changed file is read again
+	if err := ioutil.WriteFile(fname, []byte("Barbara\nAnita\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(fname, later, later); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := g.GenElement("team | regex: ^A", 5)
+	if err != nil || strings.Join(s2, ",") != "Anita" {
+		t.Errorf("FAIL. Expected Anita. Received %v, %v.", s2, err)
+	} else {
+		t.Logf("PASS. Expected Anita. Received %v.", s2)
+	}
+}
+
+// the built in and in memory dictionaries are cached too
+func Test_DictCache_Sources(t *testing.T) {
+	g := NewGenerator(1)
+	lines := []string{"red", "green", "blue"}
+	g.RegisterDictionaryLines("colour", lines)
+	lines[0] = "pink"
+
+	s, err := g.Gen("{{{ [[[ count: 3 | separator: ',' ]]] {{ country | regex: ^B }}-{{{ [[[ lastseparator: '' ]]] {{ colour }}{{ firstname }} }}} }}}", DEFAULT)
+	if exp := "Bahamas-redAARON,Bahrain-redAARON,Bangladesh-redAARON\n"; err != nil || s != exp {
+		t.Errorf("FAIL. Expected %q. Received %q, %v.", exp, s, err)
+	} else {
+		t.Logf("PASS. Expected %q. Received %q.", exp, s)
+	}
+	//one entry for each dictionary, however often the sub block is evaluated
+	if len(g.dicts.entries) != 3 {
+		t.Errorf("FAIL. Expected 3 cached dictionaries. Received %d.", len(g.dicts.entries))
+	}
+}
+
+// writeBigDictionary writes a dictionary of n lines like word000042.
+func writeBigDictionary(b *testing.B, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "word%06d\n", i)
+	}
+	fname := filepath.Join(b.TempDir(), "big.txt")
+	if err := ioutil.WriteFile(fname, []byte(sb.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return fname
+}
+
+func benchmarkBigDictionary(b *testing.B, cached bool) {
+	fname := writeBigDictionary(b, 1000000)
+	g := NewGenerator(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			g = NewGenerator(1)
+		}
+		g.RegisterDictionary("big", fname)
+		if _, err := g.GenElement("big | regex: 7$ | random", 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Dictionary1M_Uncached(b *testing.B) {
+	benchmarkBigDictionary(b, false)
+}
+
+func Benchmark_Dictionary1M_Cached(b *testing.B) {
+	benchmarkBigDictionary(b, true)
+}
+
+// quadraticFilter is how lines used to be filtered, for comparison
+func quadraticFilter(lines []string, r *regexp.Regexp) []string {
+	lines = append([]string(nil), lines...)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !r.MatchString(lines[i]) {
+			lines = append(lines[:i], lines[i+1:]...)
+		}
+	}
+	return lines
+}
+
+func benchmarkFilter(b *testing.B, n int, quadratic bool) {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("word%06d", i)
+	}
+	r := regexp.MustCompile("7$")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if quadratic {
+			quadraticFilter(lines, r)
+		} else {
+			weightedLines(lines, r)
+		}
+	}
+}
+
+func Benchmark_Filter50k_Quadratic(b *testing.B) {
+	//the old filter is too slow for 1M lines, so both are compared on 50k
+	benchmarkFilter(b, 50000, true)
+}
+
+func Benchmark_Filter50k_Linear(b *testing.B) {
+	benchmarkFilter(b, 50000, false)
+}
+
+func Benchmark_Filter1M_Linear(b *testing.B) {
+	benchmarkFilter(b, 1000000, false)
+}
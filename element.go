@@ -0,0 +1,234 @@
+package datagen
+
+import (
+	"embed"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// ElementGenerator generates the values of an element type, e.g. "country".
+// Generate is called once for every row of the block the element is in.
+type ElementGenerator interface {
+	Generate(ctx *Context) (string, error)
+}
+
+// OptionNamer is implemented by elements which name their options.  Their
+// options with these names are given to them even when a filter has the
+// same name.
+type OptionNamer interface {
+	OptionNames() []string
+}
+
+// ElementFunc lets an ordinary function be used as an ElementGenerator.
+type ElementFunc func(ctx *Context) (string, error)
+
+func (f ElementFunc) Generate(ctx *Context) (string, error) {
+	return f(ctx)
+}
+
+// Context is given to an ElementGenerator for each value.  The same Context is
+// used for all the rows of one evaluation of a block, so a generator can keep
+// what it needs between rows in State.
+type Context struct {
+	Name    string
+	Options []Option
+	Row     int //0 based row within the block
+	Count   int //the number of rows in the block
+
+	Rand      *rand.Rand
+	Generator *Generator
+	State     interface{}
+
+	pos Pos
+	row *rowScope //the values of the current row, nil outside of a block
+}
+
+// Option returns the value of the option with the key and whether it was given.
+func (ctx *Context) Option(key string) (string, bool) {
+	o := findOption(ctx.Options, strings.ToLower(key))
+	if o == nil {
+		return "", false
+	}
+	return o.Value, true
+}
+
+// Decode sets the fields of the struct pointed to by v from the options, the
+// same way block options are set.  Field names are matched ignoring case.
+func (ctx *Context) Decode(v interface{}) error {
+	return decodeOptions(ctx.Options, v)
+}
+
+// OptionError reports a bad value for the option with the key.  It is a
+// *ParseError located at the option, or at the element if the option was not
+// given.
+func (ctx *Context) OptionError(key, format string, args ...interface{}) error {
+	pos := ctx.pos
+	if o := findOption(ctx.Options, strings.ToLower(key)); o != nil {
+		pos = o.Pos
+	}
+	return newParseError(ErrBadOptionValue, pos, key, format, args...)
+}
+
+// exhaustedError is an OptionError for an element which has no more values
+// to give.  generate stops at it rather than failing.
+func (ctx *Context) exhaustedError(key, format string, args ...interface{}) error {
+	return exhaustedError{ctx.OptionError(key, format, args...).(*ParseError)}
+}
+
+// errExhausted is matched by the errors of elements which have no more
+// values to give.
+var errExhausted = errors.New("no more values")
+
+// exhaustedError is a *ParseError which also matches errExhausted
+type exhaustedError struct {
+	*ParseError
+}
+
+func (e exhaustedError) Unwrap() error {
+	return e.ParseError
+}
+
+func (e exhaustedError) Is(target error) bool {
+	return target == errExhausted
+}
+
+// maxUniqueTries is how many values are tried for a row of a unique element
+// before giving up.
+const maxUniqueTries = 1000
+
+// uniqueElement keeps an element from giving a value twice by generating
+// again until it gets a new value.  Any element can be made unique with the
+// unique option.
+type uniqueElement struct {
+	eg   ElementGenerator
+	seen map[string]bool
+}
+
+func (u *uniqueElement) Generate(ctx *Context) (string, error) {
+	for i := 0; i < maxUniqueTries; i++ {
+		v, err := u.eg.Generate(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !u.seen[v] {
+			u.seen[v] = true
+			return v, nil
+		}
+	}
+	return "", ctx.OptionError("unique", "element %s gave no new value in %d tries at row %d", ctx.Name, maxUniqueTries, ctx.Row+1)
+}
+
+// registry holds element types and filters by name.  Names not found are
+// looked up in the parent registry.
+type registry struct {
+	sync.RWMutex
+	elements map[string]ElementGenerator
+	filters  map[string]Filter
+	parent   *registry
+}
+
+func newRegistry(parent *registry) *registry {
+	return &registry{
+		elements: make(map[string]ElementGenerator),
+		filters:  make(map[string]Filter),
+		parent:   parent,
+	}
+}
+
+func (r *registry) register(name string, eg ElementGenerator) {
+	r.Lock()
+	defer r.Unlock()
+	r.elements[strings.ToLower(name)] = eg
+}
+
+func (r *registry) lookup(name string) (ElementGenerator, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	eg, ok := r.elements[name]
+	if !ok && r.parent != nil {
+		return r.parent.lookup(name)
+	}
+	return eg, ok
+}
+
+var elements = newRegistry(nil)
+
+// Register makes an element type available to all templates.  Names are not
+// case sensitive.  Registering an existing name replaces it.  Options which
+// have the name of a registered filter are taken as the filter, unless the
+// element is an OptionNamer which names them as its own.
+func Register(name string, eg ElementGenerator) {
+	elements.register(name, eg)
+}
+
+// Register makes an element type available to the templates of this
+// generator only.  It takes precedence over the package level Register.
+func (g *Generator) Register(name string, eg ElementGenerator) {
+	g.elements.register(name, eg)
+}
+
+func (g *Generator) lookup(name string) (ElementGenerator, bool) {
+	if eg, ok := g.elements.lookup(name); ok {
+		return eg, true
+	}
+	return elements.lookup(name)
+}
+
+// newContext prepares an element for generating count values.
+func (g *Generator) newContext(el *ElementNode, count int) (ElementGenerator, *Context, error) {
+	eg, ok := g.lookup(el.Name)
+	if !ok {
+		return nil, nil, newParseError(ErrUnknownElement, el.Pos, el.Name, "%q", el.Name)
+	}
+	eg, ctx := g.contextFor(eg, el, count)
+	return eg, ctx, nil
+}
+
+// contextFor prepares eg for generating count values of the element.  The
+// options which name a filter, and are not named by the element as its own,
+// are taken out of the element's options and applied to its values.
+func (g *Generator) contextFor(eg ElementGenerator, el *ElementNode, count int) (ElementGenerator, *Context) {
+	var opts []Option
+	var filters []filterCall
+	own := map[string]bool{"unique": true}
+	if on, ok := eg.(OptionNamer); ok {
+		for _, name := range on.OptionNames() {
+			own[strings.ToLower(name)] = true
+		}
+	}
+	for _, o := range el.Options {
+		if f, ok := g.lookupFilter(o.Key); ok && !own[o.Key] {
+			filters = append(filters, filterCall{f, o})
+		} else {
+			opts = append(opts, o)
+		}
+	}
+	if len(filters) > 0 {
+		eg = &filteredElement{eg: eg, filters: filters}
+	}
+	//unique applies to the filtered values
+	if findOption(opts, "unique") != nil {
+		eg = &uniqueElement{eg: eg, seen: make(map[string]bool)}
+	}
+	return eg, &Context{
+		Name:      el.Name,
+		Options:   opts,
+		Count:     count,
+		Rand:      g.rand,
+		Generator: g,
+		pos:       el.Pos,
+	}
+}
+
+// builtinData holds the bundled datasets so that they work from any directory
+//
+//go:embed country.txt firstname.txt
+var builtinData embed.FS
+
+func init() {
+	for name, fnames := range mFiles {
+		RegisterDictionaryFS(name, builtinData, fnames...)
+	}
+}
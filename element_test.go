@@ -0,0 +1,135 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// expectBadOption checks that each of the elements gives ErrBadOptionValue
+func expectBadOption(t *testing.T, ebs ...string) {
+	t.Helper()
+	for _, eb := range ebs {
+		_, err := NewGenerator(1).GenElement(eb, 1)
+		if !errors.Is(err, ErrBadOptionValue) {
+			t.Errorf("FAIL. Expected %v for %s. Received %v.", ErrBadOptionValue, eb, err)
+		} else {
+			t.Logf("PASS. Expected %v for %s. Received %v.", ErrBadOptionValue, eb, err)
+		}
+	}
+}
+
+func Test_Register(t *testing.T) {
+	Register("sku", ElementFunc(func(ctx *Context) (string, error) {
+		prefix, _ := ctx.Option("prefix")
+		return fmt.Sprintf("%s-%03d", prefix, ctx.Row+1), nil
+	}))
+
+	s, err := Gen("{{{ [[[ count: 3 | separator: ',' ]]] {{ SKU | prefix:AB }} }}}", DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := "AB-001,AB-002,AB-003\n"
+	if s != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, s)
+	}
+}
+
+func Test_Generator_Register(t *testing.T) {
+	g := NewGenerator(1)
+
+	//counts the rows with its own state, and overrides the built in country
+	g.Register("country", ElementFunc(func(ctx *Context) (string, error) {
+		n, _ := ctx.State.(int)
+		ctx.State = n + 1
+		return "country" + strconv.Itoa(n), nil
+	}))
+
+	s, err := g.GenBlock("{{{ [[[ count: 2 | separator: ' ' ]]] {{ country }}={{{ [[[ count: 2 | separator: ' ' | lastseparator: '' ]]] {{ country }} }}} }}}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := "country0=country0 country1 country1=country0 country1\n"
+	if s != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, s)
+	}
+
+	//other generators are not affected
+	s, err = NewGenerator(1).GenBlock("{{{ {{ country }} }}}")
+	if err != nil || s != "Afghanistan\n" {
+		t.Errorf("FAIL. Expected Afghanistan. Received %+v, %v.", s, err)
+	}
+}
+
+func Test_Context_OptionError(t *testing.T) {
+	g := NewGenerator(1)
+	g.Register("percent", ElementFunc(func(ctx *Context) (string, error) {
+		opts := struct{ Max int }{100}
+		if err := ctx.Decode(&opts); err != nil {
+			return "", err
+		}
+		if opts.Max > 100 {
+			return "", ctx.OptionError("max", "%d is more than 100", opts.Max)
+		}
+		return strconv.Itoa(ctx.Rand.Intn(opts.Max + 1)), nil
+	}))
+
+	for _, s := range []string{
+		"{{{ {{ percent | max: 200 }} }}}",
+		"{{{ {{ percent | max: lots }} }}}",
+	} {
+		_, err := g.Gen(s, DEFAULT)
+		var pe *ParseError
+		if !errors.As(err, &pe) || !errors.Is(err, ErrBadOptionValue) || pe.Col != 18 {
+			t.Errorf("FAIL. Expected a bad option value at column 18 for %s. Received %v.", s, err)
+		} else {
+			t.Logf("PASS. Received %v.", err)
+		}
+	}
+}
+
+func Test_Unique_AnyElement(t *testing.T) {
+	s, err := NewGenerator(1).GenElement("int | min: 1 | max: 20 | unique", 20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, v := range s {
+		seen[v] = true
+	}
+	if len(s) != 20 || len(seen) != 20 {
+		t.Errorf("FAIL. Expected 20 different numbers. Received %v.", s)
+	} else {
+		t.Logf("PASS. Expected 20 different numbers. Received %v.", s)
+	}
+
+	//only 20 numbers can be given
+	_, err = NewGenerator(1).GenElement("int | min: 1 | max: 20 | unique", 21)
+	if !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v. Received %v.", ErrBadOptionValue, err)
+	} else {
+		t.Logf("PASS. Expected %v. Received %v.", ErrBadOptionValue, err)
+	}
+
+	//unique is within one evaluation of a block
+	gen, err := NewGenerator(1).GenBlock("{{{ [[[ count: 2 | separator: ';' ]]] {{{ [[[ count: 3 | separator: ',' | lastseparator: '' ]]] {{ choice | values: 'a,b,c' | random | unique }} }}} }}}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, row := range strings.Split(strings.TrimSpace(gen), ";") {
+		v := strings.Split(row, ",")
+		sort.Strings(v)
+		if strings.Join(v, ",") != "a,b,c" {
+			t.Errorf("FAIL. Expected a, b and c in each row. Received %s.", gen)
+		}
+	}
+}
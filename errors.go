@@ -0,0 +1,121 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The kinds of template errors.  A *ParseError wraps one of these, so they can
+// be checked with errors.Is.
+var (
+	ErrUnbalancedBlock   = errors.New("unbalanced block")
+	ErrUnbalancedOptions = errors.New("unbalanced options")
+	ErrUnbalancedElement = errors.New("unbalanced element")
+	ErrUnknownElement    = errors.New("unknown element")
+	ErrBadOptionValue    = errors.New("bad option value")
+)
+
+// ParseError is returned for templates that are malformed or that use
+// unknown elements or invalid option values.  Line and Col are 1 based and
+// Snippet is the part of the template around the error.
+type ParseError struct {
+	Kind    error
+	Offset  int
+	Line    int
+	Col     int
+	Marker  string
+	Snippet string
+	Msg     string
+}
+
+func (e *ParseError) Error() string {
+	s := "datagen: "
+	if e.Line > 0 {
+		s += fmt.Sprintf("line %d, column %d: ", e.Line, e.Col)
+	}
+	s += e.Kind.Error() + ": " + e.Msg
+	if e.Snippet != "" {
+		s += fmt.Sprintf(" near %q", e.Snippet)
+	}
+	return s
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Kind
+}
+
+func newParseError(kind error, pos Pos, marker, format string, args ...interface{}) *ParseError {
+	return &ParseError{Kind: kind, Offset: int(pos), Marker: marker, Msg: fmt.Sprintf(format, args...)}
+}
+
+// snippetWidth is the number of bytes shown on either side of an error
+const snippetWidth = 20
+
+// locate fills in the line, column and snippet of the error from the source
+// the offset refers to.  Errors which are not a *ParseError or which have
+// already been located are returned as is.
+func locate(err error, src string) error {
+	var e *ParseError
+	if !errors.As(err, &e) || e.Line > 0 || e.Offset > len(src) {
+		return err
+	}
+
+	before := src[:e.Offset]
+	e.Line = strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	e.Col = utf8.RuneCountInString(before[lineStart:]) + 1
+
+	lineEnd := strings.IndexByte(src[e.Offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(src)
+	} else {
+		lineEnd += e.Offset
+	}
+	from, to := e.Offset-snippetWidth, e.Offset+len(e.Marker)+snippetWidth
+	if from < lineStart {
+		from = lineStart
+	}
+	if to > lineEnd {
+		to = lineEnd
+	}
+	for from > lineStart && !utf8.RuneStart(src[from]) {
+		from--
+	}
+	for to < lineEnd && !utf8.RuneStart(src[to]) {
+		to++
+	}
+	e.Snippet = src[from:to]
+	return e
+}
+
+// ErrBadSchema is the kind of error of a schema which is malformed, as
+// opposed to one with an unknown element or a bad option value.
+var ErrBadSchema = errors.New("bad schema")
+
+// SchemaError is returned for schemas which are malformed or which use
+// unknown element types or invalid option values.  Path is where in the
+// schema the error is, e.g. fields[2].options.regex, and Err is the kind of
+// error: ErrBadSchema, ErrUnknownElement or ErrBadOptionValue.
+type SchemaError struct {
+	File string //set by LoadSchema
+	Path string
+	Msg  string
+	Err  error
+}
+
+func (e *SchemaError) Error() string {
+	s := "datagen: "
+	if e.File != "" {
+		s += e.File + ": "
+	}
+	if e.Path != "" {
+		s += e.Path + ": "
+	}
+	return s + e.Err.Error() + ": " + e.Msg
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
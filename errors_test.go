@@ -0,0 +1,76 @@
+package datagen
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_ParseError_Kinds(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		kind error
+	}{
+		{"{{{ {{ country }}", ErrUnbalancedBlock},
+		{"{{{ {{ country }} }}} }}}", ErrUnbalancedBlock},
+		{"{{{ [[[ count: 3 {{ country }} }}}", ErrUnbalancedOptions},
+		{"{{{ count: 3 ]]] {{ country }} }}}", ErrUnbalancedOptions},
+		{"{{{ {{ country }}}", ErrUnbalancedBlock},
+		{"{{{ {{ country | regex: 'abc }} }}}", ErrUnbalancedElement},
+		{"{{{ country }} }}}", ErrUnbalancedElement},
+		{"{{{ {{ cuntry }} }}}", ErrUnknownElement},
+		{"{{{ [[[ count: three ]]] {{ country }} }}}", ErrBadOptionValue},
+		{"{{{ {{ country | regex: ^(C }} }}}", ErrBadOptionValue},
+	} {
+		_, err := Gen(tc.s, DEFAULT)
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("FAIL. Expected %v for %s. Received %v.", tc.kind, tc.s, err)
+		} else {
+			t.Logf("PASS. Expected %v for %s. Received %v.", tc.kind, tc.s, err)
+		}
+	}
+}
+
+func Test_ParseError_Position(t *testing.T) {
+	s := "line one\n{{{ [[[ count: 2 ]]]\n  {{ country }} {{ cuntry | random }}\n}}}"
+
+	_, err := Gen(s, DEFAULT)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("FAIL. Expected a *ParseError. Received %v.", err)
+	}
+
+	if pe.Line != 3 || pe.Col != 17 {
+		t.Errorf("FAIL. Expected line 3, column 17. Received line %d, column %d.", pe.Line, pe.Col)
+	}
+	if pe.Marker != "cuntry" {
+		t.Errorf("FAIL. Expected marker cuntry. Received %s.", pe.Marker)
+	}
+	if pe.Snippet != "  {{ country }} {{ cuntry | random }}" {
+		t.Errorf("FAIL. Expected the snippet to be the line of the error. Received %q.", pe.Snippet)
+	}
+	t.Logf("Received error: %v", err)
+
+	//positions are relative to what was given to GenBlockX
+	_, err = GenBlockX("\n\n  {{{ [[[ count: x ]]] }}}", DEFAULT)
+	if !errors.As(err, &pe) || pe.Line != 3 || pe.Col != 11 {
+		t.Errorf("FAIL. Expected error at line 3, column 11. Received %v.", err)
+	}
+}
+
+func Test_getSubBlock_Unbalanced(t *testing.T) {
+	_, err := getSubBlock("abcd }}} {{{ 1234 }}}", DEFAULT)
+	if !errors.Is(err, ErrUnbalancedBlock) {
+		t.Errorf("FAIL. Expected %v. Received %v.", ErrUnbalancedBlock, err)
+	}
+
+	_, err = getSubBlockOuter(" 12 {{ efgh {{ abcd}} 34 ", "{{", "}}")
+	if !errors.Is(err, ErrUnbalancedBlock) {
+		t.Errorf("FAIL. Expected %v. Received %v.", ErrUnbalancedBlock, err)
+	}
+
+	_, err = getSubBlockOuter(" 12 {{ efgh {{ abcd 34 ", "{{", "}}")
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Col != 5 || pe.Marker != "{{" {
+		t.Errorf("FAIL. Expected error at column 5 for marker {{. Received %v.", err)
+	}
+}
@@ -0,0 +1,70 @@
+package datagen
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"strings"
+)
+
+// escaper makes a value safe to put into the output.  nil leaves values as
+// they are.
+type escaper func(string) string
+
+// csvEscape quotes values which have commas, quotes, line breaks or spaces at
+// either end, as in RFC 4180.
+func csvEscape(s string) string {
+	if s == "" || !strings.ContainsAny(s, ",\"\r\n") && strings.TrimSpace(s) == s {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// jsonEscape escapes a value for use within a JSON string.  The quotes around
+// it are left to the template, so that numbers can be used as they are.
+func jsonEscape(s string) string {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	q := strings.TrimSuffix(sb.String(), "\n")
+	return q[1 : len(q)-1]
+}
+
+// xmlEscape escapes a value for use as XML text or within an attribute.
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// sqlEscape escapes a value for use within a single quoted SQL string.
+func sqlEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+var escapers = map[string]escaper{
+	"none": nil,
+	"csv":  csvEscape,
+	"json": jsonEscape,
+	"xml":  xmlEscape,
+	"sql":  sqlEscape,
+	"html": html.EscapeString,
+}
+
+// escaperFor gives the escaper for the escape option of a block.  auto picks
+// the escaping of the preset the markers are, and none for other markers.
+func escaperFor(name string, mo MarkerOptions) (escaper, bool) {
+	name = strings.ToLower(name)
+	if name == "auto" {
+		switch mo {
+		case CSV:
+			return csvEscape, true
+		case XML:
+			return xmlEscape, true
+		}
+		return nil, true
+	}
+	esc, ok := escapers[name]
+	return esc, ok
+}
@@ -0,0 +1,139 @@
+package datagen
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var nastyValues = []string{
+	"Guinea-Bissau, Republic",
+	`Dwayne "The Rock" Johnson`,
+	"<b>Tom & Jerry</b>",
+	"O'Brien",
+	" padded ",
+	"two\nlines",
+	`back\slash`,
+}
+
+func nastyGenerator() *Generator {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("nasty", nastyValues)
+	return g
+}
+
+func Test_Escape_CSV(t *testing.T) {
+	s := "{{{ [[[ count: 7 | escape: csv ]]] {{ nasty as n }},{{ seq }},{{ ref: n }} }}}"
+	gen, err := nastyGenerator().GenBlock(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(gen)).ReadAll()
+	if err != nil {
+		t.Fatalf("FAIL. Expected valid CSV. Received %v for %s", err, gen)
+	}
+	if len(records) != len(nastyValues) {
+		t.Fatalf("FAIL. Expected %d records. Received %d.", len(nastyValues), len(records))
+	}
+	for i, rec := range records {
+		if len(rec) != 3 || rec[0] != nastyValues[i] || rec[2] != nastyValues[i] {
+			t.Errorf("FAIL. Expected %q. Received %q.", nastyValues[i], rec)
+		} else {
+			t.Logf("PASS. Expected %q. Received %q.", nastyValues[i], rec)
+		}
+	}
+}
+
+func Test_Escape_JSON(t *testing.T) {
+	s := `[{{{ [[[ count: 7 | separator: ',' | lastseparator: '' | escape: json ]]] {"name": "{{ nasty }}", "id": {{ seq }}} }}}]`
+	gen, err := nastyGenerator().Gen(s, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var people []struct {
+		Name string
+		ID   int
+	}
+	if err := json.Unmarshal([]byte(gen), &people); err != nil {
+		t.Fatalf("FAIL. Expected valid JSON. Received %v for %s", err, gen)
+	}
+	for i, p := range people {
+		if p.Name != nastyValues[i] || p.ID != i+1 {
+			t.Errorf("FAIL. Expected %q, %d. Received %+v.", nastyValues[i], i+1, p)
+		}
+	}
+	if len(people) != len(nastyValues) {
+		t.Errorf("FAIL. Expected %d people. Received %d.", len(nastyValues), len(people))
+	}
+}
+
+func Test_Escape_XML(t *testing.T) {
+	s := `<people>{{{ [[[ count: 7 | escape: xml ]]] <person name="{{ nasty as n }}">{{ ref: n }}</person> }}}</people>`
+	gen, err := nastyGenerator().Gen(s, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var people struct {
+		Person []struct {
+			Name string `xml:"name,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"person"`
+	}
+	if err := xml.Unmarshal([]byte(gen), &people); err != nil {
+		t.Fatalf("FAIL. Expected valid XML. Received %v for %s", err, gen)
+	}
+	for i, p := range people.Person {
+		if p.Name != nastyValues[i] || p.Text != nastyValues[i] {
+			t.Errorf("FAIL. Expected %q. Received %+v.", nastyValues[i], p)
+		}
+	}
+	if len(people.Person) != len(nastyValues) {
+		t.Errorf("FAIL. Expected %d people. Received %d.", len(nastyValues), len(people.Person))
+	}
+}
+
+func Test_Escape_SQLAndHTML(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("name", []string{"O'Brien <Jr>"})
+
+	gen, err := g.GenBlock("{{{ [[[ escape: sql ]]] INSERT INTO t VALUES ('{{ name }}'); }}}")
+	if exp := "INSERT INTO t VALUES ('O''Brien <Jr>');\n"; err != nil || gen != exp {
+		t.Errorf("FAIL. Expected %q. Received %q, %v.", exp, gen, err)
+	}
+
+	//sub blocks escape like their enclosing block unless they say otherwise
+	gen, err = g.GenBlock("{{{ [[[ escape: html ]]] <p>{{ name }}</p>{{{ [[[ lastseparator: '' ]]] {{ name }} }}}{{{ [[[ escape: none | lastseparator: '' ]]] {{ name }} }}} }}}")
+	if exp := "<p>O&#39;Brien &lt;Jr&gt;</p>O&#39;Brien &lt;Jr&gt;O'Brien <Jr>\n"; err != nil || gen != exp {
+		t.Errorf("FAIL. Expected %q. Received %q, %v.", exp, gen, err)
+	}
+}
+
+func Test_Escape_Auto(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("name", []string{"Smith, John & Co"})
+
+	for _, tc := range []struct {
+		s   string
+		mo  MarkerOptions
+		exp string
+	}{
+		{"{{ [ escape: auto ] {name} }}", CSV, "\"Smith, John & Co\"\n"},
+		{"{{ [[ escape: auto ]] {name} }}", XML, "Smith, John &amp; Co\n"},
+		{"{{{ [[[ escape: auto ]]] {{ name }} }}}", DEFAULT, "Smith, John & Co\n"},
+	} {
+		gen, err := g.Gen(tc.s, tc.mo)
+		if err != nil || gen != tc.exp {
+			t.Errorf("FAIL. Expected %q for %s. Received %q, %v.", tc.exp, tc.s, gen, err)
+		} else {
+			t.Logf("PASS. Expected %q for %s. Received %q.", tc.exp, tc.s, gen)
+		}
+	}
+
+	_, err := g.GenBlock("{{{ [[[ escape: yaml ]]] {{ name }} }}}")
+	if !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v. Received %v.", ErrBadOptionValue, err)
+	}
+}
@@ -0,0 +1,259 @@
+package datagen
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// blockOptionsFrom applies the options of a block over the defaults of the
+// marker set.
+func blockOptionsFrom(on *OptionsNode, mo MarkerOptions) (*blockOptions, error) {
+	bo := blockOptions{
+		Count:         1,
+		Separator:     mo.Separator,
+		LastSeparator: mo.LastSeparator,
+		ElementBegin:  mo.ElementBegin,
+		ElementEnd:    mo.ElementEnd,
+	}
+	if on == nil {
+		return &bo, nil
+	}
+
+	//TODO: should add a report extra params as error option in setOptions
+	if err := decodeOptions(on.Options, &bo); err != nil {
+		return nil, err
+	}
+	return &bo, nil
+}
+
+// decodeOptions sets the fields of in from opts the same way as setOptions,
+// but reports the option that could not be set.
+func decodeOptions(opts []Option, in interface{}) error {
+	for _, o := range opts {
+		if err := setOptions(map[string]string{o.Key: o.Value}, in); err != nil {
+			return newParseError(ErrBadOptionValue, o.Pos, o.Key, "%s: %v", o.Key, err)
+		}
+	}
+	return nil
+}
+
+// findOption returns the last option with the key, or nil.
+func findOption(opts []Option, key string) *Option {
+	for i := len(opts) - 1; i >= 0; i-- {
+		if opts[i].Key == key {
+			return &opts[i]
+		}
+	}
+	return nil
+}
+
+// trimBody removes the surrounding whitespace of a block's body and the
+// whitespace next to its sub blocks.
+func trimBody(nodes []Node) []Node {
+	//text on both sides of the options list is joined first
+	var merged []Node
+	for _, n := range nodes {
+		if t, ok := n.(*TextNode); ok && len(merged) > 0 {
+			if prev, ok := merged[len(merged)-1].(*TextNode); ok {
+				merged[len(merged)-1] = &TextNode{prev.Pos, prev.Text + t.Text}
+				continue
+			}
+		}
+		merged = append(merged, n)
+	}
+	nodes = merged
+
+	var body []Node
+	for i, n := range nodes {
+		t, ok := n.(*TextNode)
+		if !ok {
+			body = append(body, n)
+			continue
+		}
+		s := t.Text
+		if _, ok := prevNode(nodes, i).(*BlockNode); ok || i == 0 {
+			s = strings.TrimLeft(s, " \t\r\n")
+		}
+		if _, ok := nextNode(nodes, i).(*BlockNode); ok || i == len(nodes)-1 {
+			s = strings.TrimRight(s, " \t\r\n")
+		}
+		if s != "" {
+			body = append(body, &TextNode{t.Pos, s})
+		}
+	}
+	return body
+}
+
+func prevNode(nodes []Node, i int) Node {
+	if i == 0 {
+		return nil
+	}
+	return nodes[i-1]
+}
+
+func nextNode(nodes []Node, i int) Node {
+	if i == len(nodes)-1 {
+		return nil
+	}
+	return nodes[i+1]
+}
+
+// genElement generates count values for an element.  Elements which run out
+// of values give fewer than count.
+func (g *Generator) genElement(el *ElementNode, count int) ([]string, error) {
+	eg, ctx, err := g.newContext(el, count)
+	if err != nil {
+		return nil, err
+	}
+	return generate(eg, ctx, count)
+}
+
+// generate gives count values of eg, or fewer if it runs out.
+func generate(eg ElementGenerator, ctx *Context, count int) ([]string, error) {
+	var data []string
+	for ctx.Row = 0; ctx.Row < count; ctx.Row++ {
+		v, err := eg.Generate(ctx)
+		if errors.Is(err, errExhausted) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, v)
+	}
+	return data, nil
+}
+
+// output is where generated data is written.  The first error writing is
+// kept and later writes are skipped, so it is only checked once per row.
+type output struct {
+	w   io.Writer
+	err error
+
+	//endRow is called after each row of the outermost block, if set, with
+	//the values of the row's elements by their place in the body
+	endRow func(values []string) error
+}
+
+func (out *output) WriteString(s string) {
+	if out.err == nil {
+		_, out.err = io.WriteString(out.w, s)
+	}
+}
+
+// evalBlock writes the data for a block, one row at a time.  parent holds the
+// values of the current row of the enclosing block, if any, and esc is the
+// escaping of the enclosing block, which sub blocks use unless they have
+// their own.  count, if more than 0, is given instead of the block's count
+// option.
+func (g *Generator) evalBlock(out *output, b *BlockNode, mo MarkerOptions, parent *rowScope, esc escaper, count int) error {
+	bo, err := blockOptionsFrom(b.Options, mo)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		bo.Count = count
+	}
+
+	if b.Options != nil {
+		if o := findOption(b.Options.Options, "escape"); o != nil {
+			var ok bool
+			if esc, ok = escaperFor(bo.Escape, mo); !ok {
+				return newParseError(ErrBadOptionValue, o.Pos, o.Key, "unknown escape %q", bo.Escape)
+			}
+		}
+	}
+
+	//a seeded block and its sub blocks give the same data wherever it is used
+	if b.Options != nil && findOption(b.Options.Options, "seed") != nil {
+		g = g.withSeed(bo.Seed)
+	}
+	if b.Options != nil {
+		if g, err = g.registerDictionaries(b.Options.Options); err != nil {
+			return err
+		}
+	}
+
+	body := trimBody(b.Body)
+
+	row := &rowScope{parent: parent, names: make(map[string]int)}
+	egs := make([]ElementGenerator, len(body))
+	ctxs := make([]*Context, len(body))
+	for i, n := range body {
+		if n, ok := n.(*ElementNode); ok {
+			if egs[i], ctxs[i], err = g.newContext(n, bo.Count); err != nil {
+				return err
+			}
+			ctxs[i].row = row
+			if n.As != "" {
+				if _, ok := row.names[n.As]; ok {
+					return newParseError(ErrBadOptionValue, n.Pos, n.As, "the name %q is used twice in the block", n.As)
+				}
+				row.names[n.As] = i
+			}
+		}
+	}
+	row.gen = func(i int) (string, error) {
+		ctxs[i].Row = row.row
+		return egs[i].Generate(ctxs[i])
+	}
+	row.values = make([]string, len(body))
+	row.state = make([]valueState, len(body))
+
+	for r := 0; r < bo.Count; r++ {
+		row.reset(r)
+		for i, n := range body {
+			switch n := n.(type) {
+			case *TextNode:
+				out.WriteString(n.Text)
+			case *ElementNode:
+				v, err := row.value(i)
+				if err != nil {
+					return err
+				}
+				//refs get the values as they were generated
+				if esc != nil {
+					v = esc(v)
+				}
+				out.WriteString(v)
+			case *BlockNode:
+				//sub blocks are generated afresh for every row
+				if err := g.evalBlock(out, n, mo, row, esc, 0); err != nil {
+					return err
+				}
+			}
+		}
+
+		if r == bo.Count-1 {
+			out.WriteString(bo.LastSeparator)
+		} else {
+			out.WriteString(bo.Separator)
+		}
+		if out.err != nil {
+			return out.err
+		}
+		if out.endRow != nil && parent == nil {
+			if err := out.endRow(row.values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evalTemplate writes the data for a template.  Text outside of blocks is
+// copied.
+func (g *Generator) evalTemplate(out *output, t *Template) error {
+	for _, n := range t.Nodes {
+		switch n := n.(type) {
+		case *TextNode:
+			out.WriteString(n.Text)
+		case *BlockNode:
+			if err := g.evalBlock(out, n, t.Markers, nil, nil, g.count); err != nil {
+				return err
+			}
+		}
+	}
+	return out.err
+}
@@ -0,0 +1,398 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Fill sets the fields of the struct pointed to by v from their datagen tags.
+// A tag is an element as it is written in a template, e.g.
+//
+//	type Person struct {
+//		Name    string    `datagen:"firstname | regex: ^C.*"`
+//		Age     int       `datagen:"int | min: 18 | max: 90"`
+//		Born    time.Time `datagen:"date | from: 1950-01-01"`
+//		Emails  []string  `datagen:"email | len: 2"`
+//		Scores  map[string]float64 `datagen:"float | len: 3 | key: firstname"`
+//		Secret  string    `datagen:"-"`
+//		Address *Address
+//	}
+//
+// Nested structs and pointers to structs are filled whether they have a tag
+// or not.  Slices, arrays and maps are filled when they have a tag; len gives
+// the number of entries, 1 if not given, and key is the element map keys are
+// made from, seq if not given.  A tag of just "len: 3" fills a slice of
+// structs.  Fields without a tag and unexported fields are left as they are.
+//
+// time.Time fields take date, time and timestamp elements, whose format and
+// layout are ignored so that the times keep their zone, or other elements
+// whose values are like 2006-01-02, 2006-01-02 15:04:05 or RFC 3339.
+// time.Duration fields take values with a unit, like 90s or 2d, as given by
+// choice or pattern, and not bare numbers.
+func Fill(v interface{}) error {
+	return defaultGenerator().Fill(v)
+}
+
+func (g *Generator) Fill(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("datagen: Fill needs a non nil pointer")
+	}
+	f := newFiller(g.begin())
+	return f.walk(rv.Elem(), rv.Elem().Type().Name(), nil, 1)
+}
+
+// FillSlice sets the slice pointed to by v to n values filled as by Fill.
+// The values are like the rows of a block, so sequences count up and unique
+// values are unique across the slice.
+func FillSlice(v interface{}, n int) error {
+	return defaultGenerator().FillSlice(v, n)
+}
+
+func (g *Generator) FillSlice(v interface{}, n int) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("datagen: FillSlice needs a non nil pointer to a slice")
+	}
+	if n < 0 {
+		return fmt.Errorf("datagen: FillSlice of %d values", n)
+	}
+
+	f := newFiller(g.begin())
+	f.count = n
+	s := reflect.MakeSlice(rv.Elem().Type(), n, n)
+	name := s.Type().Elem().Name()
+	for i := 0; i < n; i++ {
+		if err := f.walk(s.Index(i), name, nil, 1); err != nil {
+			return err
+		}
+	}
+	rv.Elem().Set(s)
+	return nil
+}
+
+// filler fills values from tags.  Each field has one context for all the
+// values it is given, so that it can keep its state between them.
+type filler struct {
+	g      *Generator
+	count  int //the number of values of the outermost type
+	fields map[string]*fieldGen
+	onPath map[reflect.Type]bool //structs being filled, to stop at cycles
+}
+
+func newFiller(g *Generator) *filler {
+	return &filler{g: g, count: 1, fields: make(map[string]*fieldGen), onPath: make(map[reflect.Type]bool)}
+}
+
+// fieldError is an error filling the field at path
+type fieldError struct {
+	path string //and ": key" for the keys of a map
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return "datagen: field " + e.path + ": " + strings.TrimPrefix(e.err.Error(), "datagen: ")
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// fieldGen generates the values of a field from its tag
+type fieldGen struct {
+	path   string
+	length int
+
+	eg  ElementGenerator //nil if the tag only has len
+	ctx *Context
+
+	keyEg  ElementGenerator
+	keyCtx *Context
+}
+
+func (fg *fieldGen) next() (string, error) {
+	v, err := fg.eg.Generate(fg.ctx)
+	fg.ctx.Row++
+	if err != nil {
+		return "", &fieldError{fg.path, err}
+	}
+	return v, nil
+}
+
+func (fg *fieldGen) nextKey() (string, error) {
+	v, err := fg.keyEg.Generate(fg.keyCtx)
+	fg.keyCtx.Row++
+	if err != nil {
+		return "", &fieldError{fg.path + ": key", err}
+	}
+	return v, nil
+}
+
+// field gives the generator of the field at path, of type t, with the tag.
+// The field is filled times times for each value of the outermost type.
+func (f *filler) field(path, tag string, t reflect.Type, times int) (*fieldGen, error) {
+	if fg, ok := f.fields[path]; ok {
+		return fg, nil
+	}
+
+	opts, err := newParser(tag, DEFAULT).parseList(item{}, "", ErrUnbalancedElement)
+	if err != nil {
+		return nil, &fieldError{path, locate(err, tag)}
+	}
+
+	fg := &fieldGen{path: path, length: 1}
+	key := "seq"
+	var elOpts []Option
+	for _, o := range opts {
+		switch o.Key {
+		case "len":
+			n, err := strconv.Atoi(o.Value)
+			if err != nil || n < 0 {
+				return nil, &fieldError{path, locate(newParseError(ErrBadOptionValue, o.Pos, o.Key, "bad len %q", o.Value), tag)}
+			}
+			fg.length = n
+		case "key":
+			key = o.Value
+		default:
+			elOpts = append(elOpts, o)
+		}
+	}
+
+	count := f.count * times * valuesPer(t, fg.length)
+	if len(elOpts) > 0 {
+		el := elementNode(elOpts[0].Pos, elOpts)
+		if holdsTime(t) {
+			f.g.timeValues(el)
+		}
+		if fg.eg, fg.ctx, err = f.g.newContext(el, count); err != nil {
+			return nil, &fieldError{path, locate(err, tag)}
+		}
+	}
+	//map keys must not repeat
+	keyEl := &ElementNode{Name: key, Options: []Option{{Key: "unique"}}}
+	if fg.keyEg, fg.keyCtx, err = f.g.newContext(keyEl, count); err != nil {
+		return nil, &fieldError{path + ": key", err}
+	}
+
+	f.fields[path] = fg
+	return fg, nil
+}
+
+// walk fills v, which is found at path.  fg is the generator from the tag of
+// the field, or nil if it has none.  v is filled times times for each value
+// of the outermost type, as it may be within slices, arrays and maps.
+func (f *filler) walk(v reflect.Value, path string, fg *fieldGen, times int) error {
+	t := v.Type()
+	hasElement := fg != nil && fg.eg != nil
+
+	if t == timeType || isBasic(t.Kind()) {
+		if !hasElement {
+			return nil
+		}
+		s, err := fg.next()
+		if err != nil {
+			return err
+		}
+		if err := setString(v, s); err != nil {
+			return &fieldError{path, err}
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			if t.Elem().Kind() != reflect.Struct && fg == nil || f.onPath[t.Elem()] {
+				return nil
+			}
+			v.Set(reflect.New(t.Elem()))
+		}
+		return f.walk(v.Elem(), path, fg, times)
+
+	case reflect.Struct:
+		if hasElement {
+			return fmt.Errorf("datagen: field %s: can not fill a struct from an element", path)
+		}
+		if f.onPath[t] {
+			return nil
+		}
+		f.onPath[t] = true
+		defer delete(f.onPath, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("datagen")
+			if tag == "-" || !v.Field(i).CanSet() {
+				continue
+			}
+			fpath := path + "." + sf.Name
+			var ffg *fieldGen
+			if tag != "" {
+				var err error
+				if ffg, err = f.field(fpath, tag, sf.Type, times); err != nil {
+					return err
+				}
+			}
+			if err := f.walk(v.Field(i), fpath, ffg, times); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if fg == nil {
+			return nil
+		}
+		s := reflect.MakeSlice(t, fg.length, fg.length)
+		for i := 0; i < fg.length; i++ {
+			if err := f.walk(s.Index(i), path+"[]", fg, times*fg.length); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+
+	case reflect.Array:
+		if fg == nil {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := f.walk(v.Index(i), path+"[]", fg, times*v.Len()); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if fg == nil {
+			return nil
+		}
+		m := reflect.MakeMapWithSize(t, fg.length)
+		for i := 0; i < fg.length; i++ {
+			k, err := fg.nextKey()
+			if err != nil {
+				return err
+			}
+			kv := reflect.New(t.Key()).Elem()
+			if err := setString(kv, k); err != nil {
+				return &fieldError{path + ": key", err}
+			}
+			ev := reflect.New(t.Elem()).Elem()
+			if err := f.walk(ev, path+"[]", fg, times*fg.length); err != nil {
+				return err
+			}
+			m.SetMapIndex(kv, ev)
+		}
+		v.Set(m)
+		return nil
+	}
+
+	if hasElement {
+		return fmt.Errorf("datagen: field %s: can not fill a %s", path, t)
+	}
+	return nil
+}
+
+func isBasic(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// valuesPer gives the number of values a field of type t takes from its tag,
+// which are length for each slice or map and the length of each array.
+func valuesPer(t reflect.Type, length int) int {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return valuesPer(t.Elem(), length)
+	case reflect.Slice, reflect.Map:
+		return length * valuesPer(t.Elem(), length)
+	case reflect.Array:
+		return t.Len() * valuesPer(t.Elem(), length)
+	}
+	return 1
+}
+
+// holdsTime reports whether t is a time.Time, or a pointer, slice, array or
+// map of them.
+func holdsTime(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	return t == timeType
+}
+
+// timeValues makes a time element give RFC3339Nano whatever format or layout
+// it has, for values which are parsed back into a time.Time.
+func (g *Generator) timeValues(el *ElementNode) {
+	eg, _ := g.lookup(el.Name)
+	if _, ok := eg.(timeElement); !ok {
+		return
+	}
+	var opts []Option
+	for _, o := range el.Options {
+		if o.Key != "format" && o.Key != "layout" {
+			opts = append(opts, o)
+		}
+	}
+	el.Options = append(opts, Option{Pos: el.Pos, Key: "format", Value: "rfc3339nano"})
+}
+
+// setString sets v from a generated value.
+func setString(v reflect.Value, s string) error {
+	t := v.Type()
+	switch t {
+	case timeType:
+		tm, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	case durationType:
+		d, err := parseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, t.Bits()); err == nil {
+			v.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, t.Bits()); err == nil {
+			v.SetUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		var n float64
+		if n, err = strconv.ParseFloat(s, t.Bits()); err == nil {
+			v.SetFloat(n)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("can not use %q for a %s", s, t)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fillAddress struct {
+	City    string `datagen:"country | regex: ^C"`
+	Zip     string `datagen:"pattern | regex: \"[0-9]{5}\""`
+	Country *fillAddress
+}
+
+type fillPerson struct {
+	ID      int64     `datagen:"seq | start: 100"`
+	Name    string    `datagen:"firstname | regex: ^C.*"`
+	Age     int       `datagen:"int|min:18|max:90"`
+	Score   float32   `datagen:"float | min: 0 | max: 5 | precision: 1"`
+	Active  bool      `datagen:"choice | values: true,false"`
+	Born    time.Time `datagen:"date | from: 1950-01-01 | to: 2000-12-31"`
+	Secret  string    `datagen:"-"`
+	Note    string
+	Tags    []string         `datagen:"choice | values: a,b,c | len: 3"`
+	Ranks   map[string]uint8 `datagen:"int | min: 1 | max: 9 | len: 2 | key: firstname"`
+	Home    fillAddress
+	Work    *fillAddress
+	Friends []fillAddress `datagen:"len: 2"`
+	secret  string        `datagen:"firstname"`
+}
+
+func Test_Fill(t *testing.T) {
+	p := fillPerson{Secret: "kept", Note: "kept"}
+	if err := NewGenerator(1).Fill(&p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if p.ID != 100 || !strings.HasPrefix(p.Name, "C") || p.Age < 18 || p.Age > 90 || p.Score < 0 || p.Score > 5 || !p.Active {
+		t.Errorf("FAIL. Expected the basic fields to be filled. Received %+v.", p)
+	}
+	if p.Born.Year() < 1950 || p.Born.Year() > 2000 {
+		t.Errorf("FAIL. Expected a birth date from 1950 to 2000. Received %v.", p.Born)
+	}
+	if p.Secret != "kept" || p.Note != "kept" || p.secret != "" {
+		t.Errorf("FAIL. Expected fields without tags to be left. Received %+v.", p)
+	}
+	if strings.Join(p.Tags, ",") != "a,b,c" || len(p.Ranks) != 2 {
+		t.Errorf("FAIL. Expected 3 tags and 2 ranks. Received %v, %v.", p.Tags, p.Ranks)
+	}
+	for k, v := range p.Ranks {
+		if k == "" || v < 1 || v > 9 {
+			t.Errorf("FAIL. Expected names ranked 1 to 9. Received %v.", p.Ranks)
+		}
+	}
+	if !strings.HasPrefix(p.Home.City, "C") || len(p.Home.Zip) != 5 || p.Work == nil || p.Work.City == "" || len(p.Friends) != 2 || p.Friends[1].Zip == "" {
+		t.Errorf("FAIL. Expected the addresses to be filled. Received %+v, %+v, %+v.", p.Home, p.Work, p.Friends)
+	}
+	//the address within an address would never end
+	if p.Home.Country != nil || p.Work.Country != nil {
+		t.Errorf("FAIL. Expected addresses not to be filled within addresses. Received %+v.", p.Home.Country)
+	}
+	t.Logf("PASS. Received %+v.", p)
+}
+
+// times are filled whatever format or layout the element has
+func Test_Fill_Times(t *testing.T) {
+	var v struct {
+		Unix    time.Time     `datagen:"timestamp | from: 2024-03-01T09:00:00Z | step: 1h | format: unix"`
+		Kitchen *time.Time    `datagen:"timestamp | from: 2024-03-01T09:30:00Z | step: 1h | layout: 3:04PM"`
+		Zoned   []time.Time   `datagen:"timestamp | from: 2024-03-01T09:00:00Z | step: 1m | tz: Asia/Kolkata | len: 2"`
+		Day     time.Time     `datagen:"date | from: 2020-01-01 | to: 2020-01-01 | format: unixmilli"`
+		Parsed  time.Time     `datagen:"choice | values: 2021-06-01 10:00:00"`
+		Wait    time.Duration `datagen:"choice | values: 2d"`
+	}
+	if err := NewGenerator(1).Fill(&v); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "2024-03-01T09:00:00Z 2024-03-01T09:30:00Z 2024-03-01T14:30:00+05:30,2024-03-01T14:31:00+05:30 2020-01-01T00:00:00Z 2021-06-01T10:00:00Z"
+	rec := fmt.Sprintf("%s %s %s,%s %s %s", v.Unix.Format(time.RFC3339), v.Kitchen.Format(time.RFC3339), v.Zoned[0].Format(time.RFC3339), v.Zoned[1].Format(time.RFC3339), v.Day.Format(time.RFC3339), v.Parsed.Format(time.RFC3339))
+	if v.Wait != 48*time.Hour {
+		t.Errorf("FAIL. Expected 48h. Received %v.", v.Wait)
+	}
+	if rec != exp {
+		t.Errorf("FAIL. Expected %s. Received %s.", exp, rec)
+	} else {
+		t.Logf("PASS. Expected %s. Received %s.", exp, rec)
+	}
+
+	//and so are the arguments of a property
+	err := NewGenerator(1).Check(func(tm time.Time) bool { return tm.Year() == 2024 }, 10, "timestamp | from: 2024-03-01 | step: 1h | format: kitchen")
+	if err != nil {
+		t.Errorf("FAIL. Expected times in 2024. Received %v.", err)
+	}
+}
+
+func Test_FillSlice(t *testing.T) {
+	var people []fillPerson
+	if err := NewGenerator(1).FillSlice(&people, 4); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(people) != 4 {
+		t.Fatalf("FAIL. Expected 4 people. Received %d.", len(people))
+	}
+
+	//the people are like the rows of a block
+	var ids, active []string
+	for _, p := range people {
+		ids = append(ids, fmt.Sprint(p.ID))
+		active = append(active, fmt.Sprint(p.Active))
+	}
+	if strings.Join(ids, ",") != "100,101,102,103" || strings.Join(active, ",") != "true,false,true,false" {
+		t.Errorf("FAIL. Expected ids to count up and active to alternate. Received %v, %v.", ids, active)
+	} else {
+		t.Logf("PASS. Received %v, %v.", ids, active)
+	}
+
+	type code struct {
+		Code string `datagen:"choice | values: x,y,z | random | unique"`
+	}
+	var codes []code
+	if err := NewGenerator(1).FillSlice(&codes, 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		seen[c.Code] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("FAIL. Expected unique codes across the slice. Received %v.", codes)
+	}
+}
+
+type fillItem struct {
+	N int    `datagen:"seq"`
+	C string `datagen:"country | regex: ^Z | unique | random"`
+}
+
+// the values of fields within slices count towards the values of the block
+func Test_Fill_NestedLen(t *testing.T) {
+	var orders []struct {
+		Items []fillItem `datagen:"len: 2"`
+	}
+	if err := NewGenerator(1).FillSlice(&orders, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if it := orders[0].Items; it[0].N != 1 || it[1].N != 2 || it[0].C == it[1].C {
+		t.Errorf("FAIL. Expected 2 numbered items of different countries. Received %+v.", it)
+	}
+
+	//there are only 2 countries for the 4 items
+	err := NewGenerator(1).FillSlice(&orders, 2)
+	if !errors.Is(err, ErrBadOptionValue) || !strings.Contains(err.Error(), "count is 4") {
+		t.Errorf("FAIL. Expected %v for count 4. Received %v.", ErrBadOptionValue, err)
+	} else {
+		t.Logf("PASS. Expected %v for count 4. Received %v.", ErrBadOptionValue, err)
+	}
+}
+
+func Test_Fill_Errors(t *testing.T) {
+	var badElement struct {
+		A string `datagen:"cuntry"`
+	}
+	var badValue struct {
+		A int `datagen:"firstname"`
+	}
+	var badLen struct {
+		A []string `datagen:"firstname | len: many"`
+	}
+	var badKind struct {
+		A chan int `datagen:"firstname"`
+	}
+	var badDuration struct {
+		A time.Duration `datagen:"int | min: 1"`
+	}
+
+	for _, tc := range []struct {
+		v    interface{}
+		kind error
+		msg  string
+	}{
+		{&badElement, ErrUnknownElement, "field .A"},
+		{&badValue, nil, "can not use \"AARON\" for a int"},
+		{&badLen, ErrBadOptionValue, "bad len"},
+		{&badKind, nil, "can not fill a chan int"},
+		{&badDuration, nil, "missing unit"},
+		{badElement, nil, "non nil pointer"},
+	} {
+		err := NewGenerator(1).Fill(tc.v)
+		if err == nil || (tc.kind != nil && !errors.Is(err, tc.kind)) || !strings.Contains(err.Error(), tc.msg) || strings.Count(err.Error(), "datagen: ") != 1 {
+			t.Errorf("FAIL. Expected an error with %q. Received %v.", tc.msg, err)
+		} else {
+			t.Logf("PASS. Expected an error with %q. Received %v.", tc.msg, err)
+		}
+	}
+}
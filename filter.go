@@ -0,0 +1,215 @@
+package datagen
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter changes the values of an element, e.g. {{ country | upper }}.  args
+// are the colon separated parts following the filter's name, as in
+// {{ country | replace: " ": "_" }}.
+type Filter interface {
+	Filter(v string, args []string) (string, error)
+}
+
+// FilterFunc lets an ordinary function be used as a Filter.
+type FilterFunc func(v string, args []string) (string, error)
+
+func (f FilterFunc) Filter(v string, args []string) (string, error) {
+	return f(v, args)
+}
+
+// RegisterFilter makes a filter available to all templates.  The options of
+// an element which have the name of a filter are filters rather than options
+// of the element, and are applied in the order they are given.  Options the
+// element names as its own, such as min for int, and unique are never
+// filters.  Names are not case sensitive.
+func RegisterFilter(name string, f Filter) {
+	elements.registerFilter(name, f)
+}
+
+// RegisterFilter makes a filter available to the templates of this generator
+// only.  It takes precedence over the package level RegisterFilter.
+func (g *Generator) RegisterFilter(name string, f Filter) {
+	g.elements.registerFilter(name, f)
+}
+
+// optionNames gives the options set by the fields of the struct, for the
+// OptionNames of the built in elements.
+func optionNames(opts interface{}) []string {
+	t := reflect.TypeOf(opts)
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = strings.ToLower(t.Field(i).Name)
+	}
+	return names
+}
+
+func (r *registry) registerFilter(name string, f Filter) {
+	r.Lock()
+	defer r.Unlock()
+	r.filters[strings.ToLower(name)] = f
+}
+
+func (r *registry) lookupFilter(name string) (Filter, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	f, ok := r.filters[name]
+	if !ok && r.parent != nil {
+		return r.parent.lookupFilter(name)
+	}
+	return f, ok
+}
+
+func (g *Generator) lookupFilter(name string) (Filter, bool) {
+	if f, ok := g.elements.lookupFilter(name); ok {
+		return f, true
+	}
+	return elements.lookupFilter(name)
+}
+
+// filterCall is a filter with the option it was given by
+type filterCall struct {
+	f Filter
+	o Option
+}
+
+// filteredElement applies filters to the values of an element.  It keeps
+// the values of the element before the filters, for shrinking.
+type filteredElement struct {
+	eg      ElementGenerator
+	filters []filterCall
+	last    [2]string         //the last value and the value it was filtered from
+	raws    map[string]string //filtered from, of the values given by Shrink
+}
+
+func (fe *filteredElement) Generate(ctx *Context) (string, error) {
+	raw, err := fe.eg.Generate(ctx)
+	if err != nil {
+		return "", err
+	}
+	v, err := fe.apply(raw)
+	if err != nil {
+		return "", err
+	}
+	fe.last = [2]string{v, raw}
+	return v, nil
+}
+
+// apply applies the filters to a value of the element
+func (fe *filteredElement) apply(v string) (string, error) {
+	var err error
+	for _, fc := range fe.filters {
+		if v, err = fc.f.Filter(v, fc.o.Args); err != nil {
+			return "", newParseError(ErrBadOptionValue, fc.o.Pos, fc.o.Key, "%s: %v", fc.o.Key, err)
+		}
+	}
+	return v, nil
+}
+
+// intArg gives args[i] as a number, or def if it is not given.
+func intArg(args []string, i int, def int) (int, error) {
+	if i >= len(args) || args[i] == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", args[i])
+	}
+	return n, nil
+}
+
+func title(v string, _ []string) (string, error) {
+	rs := []rune(strings.ToLower(v))
+	for i, c := range rs {
+		if i == 0 || !unicode.IsLetter(rs[i-1]) && rs[i-1] != '\'' {
+			rs[i] = unicode.ToTitle(c)
+		}
+	}
+	return string(rs), nil
+}
+
+func trim(v string, args []string) (string, error) {
+	if len(args) > 0 && args[0] != "" {
+		return strings.Trim(v, args[0]), nil
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// truncate: n keeps the first n characters
+func truncate(v string, args []string) (string, error) {
+	n, err := intArg(args, 0, -1)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("expected truncate: length")
+	}
+	if utf8.RuneCountInString(v) <= n {
+		return v, nil
+	}
+	return string([]rune(v)[:n]), nil
+}
+
+// pad returns a filter which pads to a width, with spaces or the character
+// given, on the right or left.
+func pad(left bool) FilterFunc {
+	return func(v string, args []string) (string, error) {
+		n, err := intArg(args, 0, -1)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("expected a width")
+		}
+		fill := " "
+		if len(args) > 1 && args[1] != "" {
+			fill = args[1]
+		}
+		missing := n - utf8.RuneCountInString(v)
+		if missing <= 0 {
+			return v, nil
+		}
+		padding := strings.Repeat(fill, missing)
+		padding = string([]rune(padding)[:missing])
+		if left {
+			return padding + v, nil
+		}
+		return v + padding, nil
+	}
+}
+
+// replace: old: new replaces all of old with new
+func replace(v string, args []string) (string, error) {
+	if len(args) != 2 || args[0] == "" {
+		return "", fmt.Errorf("expected replace: old: new")
+	}
+	return strings.ReplaceAll(v, args[0], args[1]), nil
+}
+
+func init() {
+	for name, f := range map[string]FilterFunc{
+		"upper": func(v string, _ []string) (string, error) { return strings.ToUpper(v), nil },
+		"lower": func(v string, _ []string) (string, error) { return strings.ToLower(v), nil },
+		"title": title,
+		"trim":  trim,
+
+		"truncate": truncate,
+		"pad":      pad(false),
+		"lpad":     pad(true),
+		"replace":  replace,
+
+		"sha256": func(v string, _ []string) (string, error) {
+			sum := sha256.Sum256([]byte(v))
+			return hex.EncodeToString(sum[:]), nil
+		},
+		"base64": func(v string, _ []string) (string, error) {
+			return base64.StdEncoding.EncodeToString([]byte(v)), nil
+		},
+		"urlencode": func(v string, _ []string) (string, error) { return url.QueryEscape(v), nil },
+	} {
+		RegisterFilter(name, f)
+	}
+}
@@ -0,0 +1,150 @@
+package datagen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_Filters(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterDictionaryLines("name", []string{"  mary ann o'neil  "})
+
+	for _, tc := range []struct {
+		eb  string
+		exp string
+	}{
+		{"country | upper", "AFGHANISTAN"},
+		{"country | lower", "afghanistan"},
+		{"name | trim | title", "Mary Ann O'neil"},
+		{"name | trim: ' ly'", "mary ann o'nei"},
+		{"country | truncate: 5", "Afgha"},
+		{"country | truncate: 50", "Afghanistan"},
+		{"country | pad: 14", "Afghanistan   "},
+		{"country | pad: 14: .", "Afghanistan..."},
+		{"seq | lpad: 5: 0", "00001"},
+		{"name | trim | replace: \" \": \"_\"", "mary_ann_o'neil"},
+		{"country | sha256", "5dbddf911f9e565299428e948a92ea5d1943c4099db883cc4491cb32cc757de8"},
+		{"country | base64", "QWZnaGFuaXN0YW4="},
+		{"name | trim | urlencode", "mary+ann+o%27neil"},
+		//filters are applied in order
+		{"country | upper | truncate: 3 | replace: A: 4", "4FG"},
+		{"country | truncate: 3 | upper | regex: ^Al", "ALB"},
+	} {
+		s, err := g.GenElement(tc.eb, 1)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		if len(s) != 1 || s[0] != tc.exp {
+			t.Errorf("FAIL. Expected %q for %s. Received %q.", tc.exp, tc.eb, s)
+		} else {
+			t.Logf("PASS. Expected %q for %s. Received %q.", tc.exp, tc.eb, s)
+		}
+	}
+}
+
+func Test_Filters_RefsAndUnique(t *testing.T) {
+	s := "{{{ [[[ count: 2 | separator: ';' ]]] {{ firstname as fn | title }} {{ ref: fn | lower }} }}}"
+	gen, err := NewGenerator(1).GenBlock(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "Aaron aaron;Abdul abdul\n"
+	if gen != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, gen)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, gen)
+	}
+
+	//unique is checked on the filtered values, and there are only 26 letters
+	_, err = NewGenerator(1).GenElement("firstname | random | truncate: 1 | unique", 27)
+	if !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v. Received %v.", ErrBadOptionValue, err)
+	}
+}
+
+func Test_RegisterFilter(t *testing.T) {
+	g := NewGenerator(1)
+	g.RegisterFilter("reverse", FilterFunc(func(v string, _ []string) (string, error) {
+		rs := []rune(v)
+		for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+			rs[i], rs[j] = rs[j], rs[i]
+		}
+		return string(rs), nil
+	}))
+	s, err := g.GenElement("country | reverse | lower", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := "natsinahgfa,ainabla"; strings.Join(s, ",") != exp {
+		t.Errorf("FAIL. Expected %s. Received %v.", exp, s)
+	} else {
+		t.Logf("PASS. Expected %s. Received %v.", exp, s)
+	}
+
+	//only this generator knows the filter, so for others it is an option
+	s, err = NewGenerator(1).GenElement("country | reverse", 1)
+	if err != nil || s[0] != "Afghanistan" {
+		t.Errorf("FAIL. Expected Afghanistan. Received %v, %v.", s, err)
+	}
+}
+
+// skuElement names upper as its own option
+type skuElement struct{}
+
+func (skuElement) Generate(ctx *Context) (string, error) {
+	n, _ := ctx.Option("upper")
+	return "sku-" + n, nil
+}
+
+func (skuElement) OptionNames() []string {
+	return []string{"Upper"}
+}
+
+// filters do not hide the options an element names as its own
+func Test_RegisterFilter_OptionName(t *testing.T) {
+	g := NewGenerator(1)
+	bang := FilterFunc(func(v string, _ []string) (string, error) { return v + "!", nil })
+	for _, name := range []string{"min", "regex", "unique", "v", "to"} {
+		g.RegisterFilter(name, bang)
+	}
+	g.Register("sku", skuElement{})
+	g.Register("plain", ElementFunc(func(ctx *Context) (string, error) { return "sku", nil }))
+
+	for _, tc := range []struct {
+		eb  string
+		exp string
+	}{
+		{"int | min: 5 | max: 5", "5"},
+		{"country | regex: ^Af | unique", "Afghanistan"},
+		{"date | from: 2020-01-01 | to: 2020-01-01", "2020-01-01"},
+		{"country | min", "Afghanistan!"},
+		{"sku | upper: 3 | lower", "sku-3"},
+		{"plain | upper | min", "SKU!"},
+	} {
+		s, err := g.GenElement(tc.eb, 1)
+		if err != nil || s[0] != tc.exp {
+			t.Errorf("FAIL. Expected %s for %s. Received %v, %v.", tc.exp, tc.eb, s, err)
+		} else {
+			t.Logf("PASS. Expected %s for %s. Received %v.", tc.exp, tc.eb, s)
+		}
+	}
+}
+
+func Test_Filters_BadArgs(t *testing.T) {
+	for _, eb := range []string{
+		"country | truncate",
+		"country | truncate: x",
+		"country | pad: -1",
+		"country | replace: a",
+	} {
+		_, err := NewGenerator(1).GenElement(eb, 1)
+		var pe *ParseError
+		if !errors.As(err, &pe) || !errors.Is(err, ErrBadOptionValue) || pe.Col != 11 {
+			t.Errorf("FAIL. Expected %v at column 11 for %s. Received %v.", ErrBadOptionValue, eb, err)
+		} else {
+			t.Logf("PASS. Expected %v for %s. Received %v.", ErrBadOptionValue, eb, err)
+		}
+	}
+}
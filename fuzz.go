@@ -0,0 +1,106 @@
+package datagen
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Seeds gives n values of the template, which is the body of a block, e.g.
+// `{"name": "{{ firstname }}", "country": "{{ country | random }}"}`.  Each
+// value is a row of the block, so sequences count up from one value to the
+// next.
+func Seeds(template string, mo MarkerOptions, n int) ([]string, error) {
+	return defaultGenerator().Seeds(template, mo, n)
+}
+
+func (g *Generator) Seeds(template string, mo MarkerOptions, n int) ([]string, error) {
+	g = g.begin()
+	b, err := parseBody(template, 0, len(template), mo)
+	if err != nil {
+		return nil, err
+	}
+	b.Options = &OptionsNode{Options: []Option{
+		{Key: "count", Value: strconv.Itoa(n)},
+		{Key: "separator"},
+		{Key: "lastseparator"},
+	}}
+
+	var sb strings.Builder
+	seeds := make([]string, 0, n)
+	out := &output{w: &sb, endRow: func([]string) error {
+		seeds = append(seeds, sb.String())
+		sb.Reset()
+		return nil
+	}}
+	if err := g.evalBlock(out, b, mo, nil, nil, 0); err != nil {
+		return nil, locate(err, template)
+	}
+	return seeds, nil
+}
+
+// SeedAdder is what AddSeeds adds seeds to.  *testing.F is a SeedAdder.
+type SeedAdder interface {
+	Add(args ...interface{})
+}
+
+// AddSeeds adds n values of the template to the seed corpus of a fuzz test,
+// so that the fuzz target starts from realistic input, e.g.
+//
+//	func FuzzParseName(f *testing.F) {
+//		datagen.AddSeeds(f, "{{ firstname | random | title }} {{ country }}", 20)
+//		f.Fuzz(func(t *testing.T, s string) { ... })
+//	}
+//
+// The template is the body of a block with the DEFAULT markers, as for Seeds.
+// The fuzz target must take a single string.
+func AddSeeds(f SeedAdder, template string, n int) error {
+	return defaultGenerator().AddSeeds(f, template, n)
+}
+
+func (g *Generator) AddSeeds(f SeedAdder, template string, n int) error {
+	seeds, err := g.Seeds(template, DEFAULT, n)
+	if err != nil {
+		return err
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	return nil
+}
+
+// WriteCorpus writes n values of the template to dir as corpus files of a
+// fuzz test, in the encoding used by go test.  dir is usually
+// testdata/fuzz/FuzzXxx within the package of the fuzz test FuzzXxx.  The
+// files are named by their contents like those go test writes, so writing
+// the same values again does not add files.
+func WriteCorpus(dir, template string, n int) error {
+	return defaultGenerator().WriteCorpus(dir, template, n)
+}
+
+func (g *Generator) WriteCorpus(dir, template string, n int) error {
+	seeds, err := g.Seeds(template, DEFAULT, n)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, s := range seeds {
+		data := corpusFile(s)
+		name := fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// corpusFile encodes a string as a corpus file
+func corpusFile(s string) []byte {
+	return []byte("go test fuzz v1\nstring(" + strconv.Quote(s) + ")\n")
+}
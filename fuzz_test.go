@@ -0,0 +1,96 @@
+package datagen
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_Seeds(t *testing.T) {
+	seeds, err := NewGenerator(1).Seeds(`{"id": {{ seq }}, "name": "{{ firstname }}", "tags": [{{{ [[[ count: 2 | separator: ',' | lastseparator: '' ]]] "{{ choice | values: a,b }}" }}}]}`, DEFAULT, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := []string{
+		`{"id": 1, "name": "AARON", "tags": ["a","b"]}`,
+		`{"id": 2, "name": "ABDUL", "tags": ["a","b"]}`,
+		`{"id": 3, "name": "ABE", "tags": ["a","b"]}`,
+	}
+	if strings.Join(seeds, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("FAIL. Expected %q. Received %q.", exp, seeds)
+	} else {
+		t.Logf("PASS. Expected %q. Received %q.", exp, seeds)
+	}
+
+	//the count of the generator is for templates, not for seeds
+	g := NewGenerator(1)
+	g.SetCount(5)
+	if seeds, err = g.Seeds("{{ firstname }}", DEFAULT, 2); err != nil || len(seeds) != 2 {
+		t.Errorf("FAIL. Expected 2 seeds. Received %q, %v.", seeds, err)
+	}
+}
+
+type seedRecorder []interface{}
+
+func (r *seedRecorder) Add(args ...interface{}) {
+	*r = append(*r, args...)
+}
+
+func Test_AddSeeds(t *testing.T) {
+	var r seedRecorder
+	if err := NewGenerator(1).AddSeeds(&r, "{{ firstname }} from {{ country }}", 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(r) != "[AARON from Afghanistan ABDUL from Albania]" {
+		t.Errorf("FAIL. Expected 2 seeds. Received %q.", r)
+	}
+
+	if err := NewGenerator(1).AddSeeds(&r, "{{ cuntry }}", 2); err == nil {
+		t.Errorf("FAIL. Expected an error for an unknown element.")
+	}
+}
+
+func Test_WriteCorpus(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "testdata", "fuzz", "FuzzName")
+	tmpl := `{{ firstname | title }} "{{ country }}"\n`
+	if err := NewGenerator(1).WriteCorpus(dir, tmpl, 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	//the same values are the same files
+	if err := NewGenerator(1).WriteCorpus(dir, tmpl, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 3 {
+		t.Fatalf("FAIL. Expected 3 corpus files. Received %d, %v.", len(files), err)
+	}
+	data := []byte("go test fuzz v1\nstring(\"Aaron \\\"Afghanistan\\\"\\\\n\")\n")
+	name := fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil || string(b) != string(data) {
+		t.Errorf("FAIL. Expected %s to hold %q. Received %q, %v.", name, data, b, err)
+	} else {
+		t.Logf("PASS. Expected %s to hold %q.", name, data)
+	}
+}
+
+func FuzzJSONEscape(f *testing.F) {
+	if err := AddSeeds(f, `{{ firstname | title }} "{{ country }}" <{{ text | charset: special }}>`, 20); err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip()
+		}
+		var back string
+		if err := json.Unmarshal([]byte(`"`+jsonEscape(s)+`"`), &back); err != nil || back != s {
+			t.Errorf("FAIL. Expected %q back. Received %q, %v.", s, back, err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package datagen
+
+import (
+	"math/rand"
+)
+
+// Generator generates data with its own source of random numbers.  The same
+// template and seed always give the same output.  A Generator must not be used
+// by more than one goroutine at a time; use a Generator for each instead.
+//
+// The package level functions like Gen use a new Generator on every call with
+// a seed taken from math/rand.
+type Generator struct {
+	rand     *rand.Rand
+	elements *registry
+	dir      string     //directory of the template file, if any
+	dicts    *dictCache //shared by the copies of the generator
+	count    int        //overrides the count of outermost blocks if set
+
+	//named sequences, for one call of Gen
+	seqs map[string]int64
+}
+
+// NewGenerator returns a Generator whose random numbers come from seed.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		rand:     rand.New(rand.NewSource(seed)),
+		elements: newRegistry(nil),
+		dicts:    newDictCache(),
+	}
+}
+
+// Seed resets the random numbers of the generator to those given by seed.
+func (g *Generator) Seed(seed int64) {
+	g.rand.Seed(seed)
+}
+
+// SetCount makes the outermost blocks of templates give n rows, whatever
+// their count option is.  Sub blocks keep their counts.  A count of 0 uses
+// the count options again.
+func (g *Generator) SetCount(n int) {
+	g.count = n
+}
+
+// withSeed returns a copy of the generator which has its own random numbers
+// from seed.
+func (g *Generator) withSeed(seed int64) *Generator {
+	c := *g
+	c.rand = rand.New(rand.NewSource(seed))
+	return &c
+}
+
+// begin returns a copy of the generator for one call of Gen and the like.
+func (g *Generator) begin() *Generator {
+	c := *g
+	c.seqs = make(map[string]int64)
+	return &c
+}
+
+// withRegistry returns a copy of the generator where elements registered on
+// the copy do not affect the original.
+func (g *Generator) withRegistry() *Generator {
+	c := *g
+	c.elements = newRegistry(g.elements)
+	return &c
+}
+
+func defaultGenerator() *Generator {
+	return NewGenerator(rand.Int63())
+}
@@ -0,0 +1,127 @@
+package datagen
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+var goldenTests = []struct {
+	name string
+	s    string
+	mo   MarkerOptions
+	seed int64
+}{
+	{"firstname_random", "{{{ [[[ count: 10 ]]] {{ firstname | random }} }}}", DEFAULT, 1},
+	{"country_regex_random", "{{{ [[[ count: 10 | separator: ', ' ]]] {{ country | regex: ^[A-D] | random }} }}}", DEFAULT, 7},
+	{"nested", "people:\n{{{ [[[ count: 3 ]]] {{ firstname | random }} from {{ country | random }} visited [ {{{ [[[ count: 2 | separator: ',' | lastseparator: '' ]]] {{ country | random }} }}} ] }}}", DEFAULT, 42},
+	{"csv", "{{ [count:5] { firstname | random } }}", CSV, 3},
+	{"dollar", "$( $[count:5]$ ${ country | random }$ )$", DOLLAR, 5},
+	{"seed_option", "{{{ [[[ count: 3 | seed: 99 ]]] {{ firstname | random }} }}}", DEFAULT, 1},
+}
+
+func Test_Generator_Golden(t *testing.T) {
+	for _, tc := range goldenTests {
+		gen, err := NewGenerator(tc.seed).Gen(tc.s, tc.mo)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.name, err)
+			continue
+		}
+
+		golden := filepath.Join("testdata", "golden", tc.name+".golden")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := ioutil.WriteFile(golden, []byte(gen), 0644); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		exp, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if gen != string(exp) {
+			t.Errorf("FAIL. %s: Expected %+v. Received %+v.", tc.name, string(exp), gen)
+		} else {
+			t.Logf("PASS. %s: Received %+v.", tc.name, gen)
+		}
+	}
+}
+
+func Test_Generator_Seed(t *testing.T) {
+	g := NewGenerator(11)
+	first := g.TextGen(TextData{MinSize: 5, MaxSize: 10, Count: 5})
+	g.Seed(11)
+	second := g.TextGen(TextData{MinSize: 5, MaxSize: 10, Count: 5})
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("FAIL. Expected the same text after seeding again. Received %v and %v.", first, second)
+			break
+		}
+	}
+}
+
+func Test_Generator_SeedOption(t *testing.T) {
+	//the package level Gen is not seeded, but the block is
+	block := "{{{ [[[ count: 5 | seed: 42 ]]] {{ firstname | random }} {{ country | random }} }}}"
+
+	first, err := Gen(block, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := Gen(block, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Errorf("FAIL. Expected the same output for the same seed. Received %+v and %+v.", first, second)
+	} else {
+		t.Logf("PASS. Received %+v.", first)
+	}
+}
+
+func Test_Generator_Parallel(t *testing.T) {
+	block := "{{{ [[[ count: 100 ]]] {{ firstname | random }} }}}"
+	exp, err := NewGenerator(8).GenBlock(block)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = NewGenerator(8).GenBlock(block)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, s := range results {
+		if s != exp {
+			t.Errorf("FAIL. Expected generator %d to give the same output as a sequential run.", i)
+		}
+	}
+}
+
+func Test_Generator_SetCount(t *testing.T) {
+	g := NewGenerator(1)
+	g.SetCount(3)
+	s, err := g.Gen("{{{ [[[ count: 1 | separator: ';' ]]] {{ seq }}:{{{ [[[ count: 2 | separator: ',' | lastseparator: '' ]]] {{ seq }} }}} }}}", DEFAULT)
+	exp := "1:1,2;2:1,2;3:1,2\n"
+	if err != nil || s != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v, %v.", exp, s, err)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, s)
+	}
+}
@@ -0,0 +1,178 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// numberOptions are the options of the int and float elements, e.g.
+// {{ int | min: 1 | max: 100 | dist: normal | mean: 40 | stddev: 10 }}
+type numberOptions struct {
+	Min, Max  float64
+	Step      float64 //values are Min plus a multiple of Step
+	Precision int     //digits after the decimal point for floats
+	Dist      string  //uniform, normal, exponential or zipf
+	Mean      float64 //normal and exponential
+	StdDev    float64 //normal
+	Rate      float64 //exponential, 1/(Mean-Min) if not given
+	S, V      float64 //zipf, as in rand.NewZipf
+}
+
+// numberElement gives random numbers, integers when isInt is set.
+type numberElement struct {
+	isInt bool
+}
+
+type numberState struct {
+	opts numberOptions
+	zipf *rand.Zipf
+}
+
+func (ne numberElement) Generate(ctx *Context) (string, error) {
+	st, ok := ctx.State.(*numberState)
+	if !ok {
+		var err error
+		if st, err = ne.prepare(ctx); err != nil {
+			return "", err
+		}
+		ctx.State = st
+	}
+
+	o := st.opts
+	var v float64
+	switch o.Dist {
+	case "normal":
+		v = sampleIn(o.Min, o.Max, func() float64 { return ctx.Rand.NormFloat64()*o.StdDev + o.Mean })
+	case "exponential":
+		v = sampleIn(o.Min, o.Max, func() float64 { return o.Min + ctx.Rand.ExpFloat64()/o.Rate })
+	case "zipf":
+		v = o.Min + float64(st.zipf.Uint64())*o.Step
+	default:
+		if o.Step > 0 {
+			v = o.Min + float64(ctx.Rand.Int63n(steps(o)+1))*o.Step
+		} else {
+			v = o.Min + ctx.Rand.Float64()*(o.Max-o.Min)
+		}
+	}
+
+	//values of the continuous distributions are moved onto a step
+	if o.Step > 0 {
+		v = o.Min + math.Round((v-o.Min)/o.Step)*o.Step
+		if v > o.Max {
+			v -= o.Step
+		}
+	}
+
+	if ne.isInt {
+		return strconv.FormatInt(int64(math.Round(v)), 10), nil
+	}
+	return strconv.FormatFloat(v, 'f', o.Precision, 64), nil
+}
+
+func (ne numberElement) prepare(ctx *Context) (*numberState, error) {
+	st := &numberState{opts: numberOptions{Max: 1, Precision: 2, Dist: "uniform", S: 1.1, V: 1}}
+	if ne.isInt {
+		st.opts.Max = 100
+		st.opts.Step = 1
+	}
+	if err := ctx.Decode(&st.opts); err != nil {
+		return nil, err
+	}
+
+	o := &st.opts
+	if o.Max < o.Min {
+		return nil, ctx.OptionError("max", "max %v is less than min %v", o.Max, o.Min)
+	}
+	if o.Step < 0 || (ne.isInt && (o.Step < 1 || o.Step != math.Trunc(o.Step))) {
+		return nil, ctx.OptionError("step", "bad step %v", o.Step)
+	}
+	if math.IsInf(o.Max-o.Min, 0) || math.IsNaN(o.Max-o.Min) {
+		return nil, ctx.OptionError("max", "the range from min %v to max %v is too large", o.Min, o.Max)
+	}
+	if o.Step > 0 && !stepsFit(*o) {
+		return nil, ctx.OptionError("max", "there are too many steps of %v from min %v to max %v", o.Step, o.Min, o.Max)
+	}
+	if o.Precision < 0 {
+		return nil, ctx.OptionError("precision", "precision can not be negative")
+	}
+	o.Dist = strings.ToLower(o.Dist)
+	if _, ok := ctx.Option("mean"); !ok {
+		o.Mean = (o.Min + o.Max) / 2
+		if o.Dist == "exponential" || o.Dist == "exp" {
+			o.Mean = o.Min + (o.Max-o.Min)/4
+		}
+	}
+
+	switch o.Dist {
+	case "uniform":
+	case "normal", "gaussian":
+		o.Dist = "normal"
+		if _, ok := ctx.Option("stddev"); !ok {
+			o.StdDev = (o.Max - o.Min) / 6
+		}
+		if o.StdDev < 0 {
+			return nil, ctx.OptionError("stddev", "stddev can not be negative")
+		}
+	case "exponential", "exp":
+		o.Dist = "exponential"
+		if _, ok := ctx.Option("rate"); !ok {
+			if o.Mean <= o.Min {
+				return nil, ctx.OptionError("mean", "mean %v must be more than min %v", o.Mean, o.Min)
+			}
+			o.Rate = 1 / (o.Mean - o.Min)
+		}
+		if o.Rate <= 0 {
+			return nil, ctx.OptionError("rate", "rate must be more than 0")
+		}
+	case "zipf":
+		if o.S <= 1 {
+			return nil, ctx.OptionError("s", "s must be more than 1")
+		}
+		if o.V < 1 {
+			return nil, ctx.OptionError("v", "v must be 1 or more")
+		}
+		if o.Step == 0 {
+			o.Step = math.Pow(10, -float64(o.Precision))
+			if !stepsFit(*o) {
+				return nil, ctx.OptionError("max", "there are too many steps of %v from min %v to max %v", o.Step, o.Min, o.Max)
+			}
+		}
+		st.zipf = rand.NewZipf(ctx.Rand, o.S, o.V, uint64(steps(*o)))
+	default:
+		return nil, ctx.OptionError("dist", "unknown distribution %q", o.Dist)
+	}
+	return st, nil
+}
+
+// steps gives the number of steps between min and max.
+func steps(o numberOptions) int64 {
+	return int64(math.Floor((o.Max-o.Min)/o.Step + 1e-9))
+}
+
+// stepsFit tells whether the number of steps between min and max, plus
+// one, can be counted in an int64.
+func stepsFit(o numberOptions) bool {
+	n := (o.Max - o.Min) / o.Step
+	return !math.IsInf(n, 0) && !math.IsNaN(n) && n < math.MaxInt64/2
+}
+
+// sampleIn draws from sample until a value within min and max is found.  If
+// that does not happen soon, the last value is clamped.
+func sampleIn(min, max float64, sample func() float64) float64 {
+	v := sample()
+	for i := 0; i < 100 && (v < min || v > max); i++ {
+		v = sample()
+	}
+	return math.Max(min, math.Min(max, v))
+}
+
+func (numberElement) OptionNames() []string {
+	return optionNames(numberOptions{})
+}
+
+func init() {
+	Register("int", numberElement{isInt: true})
+	Register("float", numberElement{})
+}
@@ -0,0 +1,132 @@
+package datagen
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// genNumbers generates n numbers for the element and parses them.
+func genNumbers(t *testing.T, eb string, n int) []float64 {
+	s, err := NewGenerator(1).GenElement(eb, n)
+	if err != nil {
+		t.Fatalf("Unexpected error for %s: %v", eb, err)
+	}
+	var a []float64
+	for _, v := range s {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", eb, err)
+		}
+		a = append(a, f)
+	}
+	return a
+}
+
+func mean(a []float64) float64 {
+	sum := 0.0
+	for _, v := range a {
+		sum += v
+	}
+	return sum / float64(len(a))
+}
+
+func Test_IntElement(t *testing.T) {
+	for _, tc := range []struct {
+		eb             string
+		min, max, step float64
+	}{
+		{"int", 0, 100, 1},
+		{"int | min: 1 | max: 6", 1, 6, 1},
+		{"int | min: -50 | max: 50 | step: 5", -50, 50, 5},
+		{"int | min: 18 | max: 90 | dist: normal | mean: 40 | stddev: 10", 18, 90, 1},
+		{"int | min: 1 | max: 1000 | dist: exponential", 1, 1000, 1},
+		{"int | min: 1 | max: 50 | dist: zipf", 1, 50, 1},
+	} {
+		for _, v := range genNumbers(t, tc.eb, 500) {
+			if v < tc.min || v > tc.max || math.Mod(v-tc.min, tc.step) != 0 {
+				t.Errorf("FAIL. Expected %v to %v in steps of %v for %s. Received %v.", tc.min, tc.max, tc.step, tc.eb, v)
+				break
+			}
+		}
+	}
+}
+
+func Test_FloatElement(t *testing.T) {
+	s, err := NewGenerator(1).GenElement("float | min: 0 | max: 1 | precision: 2", 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, v := range s {
+		f, _ := strconv.ParseFloat(v, 64)
+		if len(v) != 4 || !strings.HasPrefix(v, "0.") && v != "1.00" || f < 0 || f > 1 {
+			t.Errorf("FAIL. Expected 0.00 to 1.00. Received %v.", v)
+			break
+		}
+	}
+
+	for _, v := range genNumbers(t, "float | min: 9.99 | max: 99.99 | step: 0.5 | precision: 2", 200) {
+		if v < 9.99 || v > 99.99 || math.Abs(math.Mod(v-9.99+1e-9, 0.5)) > 1e-6 {
+			t.Errorf("FAIL. Expected 9.99 to 99.99 in steps of 0.5. Received %v.", v)
+			break
+		}
+	}
+}
+
+func Test_NumberDistributions(t *testing.T) {
+	normal := genNumbers(t, "float | min: 0 | max: 1000 | dist: normal | mean: 200 | stddev: 20 | precision: 4", 2000)
+	if m := mean(normal); math.Abs(m-200) > 5 {
+		t.Errorf("FAIL. Expected a mean near 200. Received %v.", m)
+	}
+
+	exp := genNumbers(t, "float | min: 10 | max: 100000 | dist: exp | mean: 60 | precision: 4", 2000)
+	if m := mean(exp); math.Abs(m-60) > 5 {
+		t.Errorf("FAIL. Expected a mean near 60. Received %v.", m)
+	}
+
+	//with zipf the smallest value is the most common by far
+	counts := make(map[float64]int)
+	for _, v := range genNumbers(t, "int | min: 1 | max: 100 | dist: zipf | s: 2", 2000) {
+		counts[v]++
+	}
+	if counts[1] < 1000 || counts[1] < 2*counts[2] {
+		t.Errorf("FAIL. Expected 1 to be most common. Received counts %v.", counts)
+	}
+
+	uniform := genNumbers(t, "int | min: 1 | max: 100", 2000)
+	if m := mean(uniform); math.Abs(m-50.5) > 3 {
+		t.Errorf("FAIL. Expected a mean near 50.5. Received %v.", m)
+	}
+}
+
+func Test_NumberElement_BadOptions(t *testing.T) {
+	expectBadOption(t,
+		"int | min: 10 | max: 5",
+		"int | step: 0.5",
+		"int | max: ten",
+		"float | dist: poisson",
+		"float | dist: normal | stddev: -1",
+		"int | dist: zipf | s: 1",
+		"int | dist: exponential | min: 5 | mean: 5",
+		"int | max: 1e30",
+		"int | min: -9000000000000000000 | max: 9000000000000000000",
+		"float | max: 1e308 | step: 1e-308",
+		"float | min: -1e308 | max: 1e308",
+		"float | max: 1e300 | dist: zipf",
+	)
+}
+
+func Test_NumberElement_Block(t *testing.T) {
+	block := "{{{ [[[ count: 3 | seed: 5 ]]] {{ firstname | random }},{{ int | min: 1 | max: 10 }},{{ float | min: 0 | max: 500 | precision: 2 }} }}}"
+	first, err := Gen(block, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, _ := Gen(block, DEFAULT)
+	if first != second || strings.Count(first, "\n") != 3 {
+		t.Errorf("FAIL. Expected the same 3 rows for the same seed. Received %+v and %+v.", first, second)
+	} else {
+		t.Logf("PASS. Received %+v.", first)
+	}
+}
@@ -0,0 +1,341 @@
+package datagen
+
+import (
+	"strings"
+	"unicode"
+)
+
+type itemType int
+
+const (
+	itemEOF itemType = iota
+	itemError
+	itemText
+	itemBlockBegin
+	itemBlockEnd
+	itemOptionsBegin
+	itemOptionsEnd
+	itemElementBegin
+	itemElementEnd
+
+	//used within options and elements
+	itemWord
+	itemString
+	itemPipe
+	itemColon
+	itemEnd
+)
+
+type item struct {
+	typ itemType
+	pos Pos
+	val string
+}
+
+// lexer splits a template into items.  The parser pulls items from it one at
+// a time since the markers in effect change as blocks override them.
+type lexer struct {
+	src string
+	pos int
+	mo  MarkerOptions
+}
+
+type markerItem struct {
+	typ    itemType
+	marker string
+}
+
+// marker returns the longest marker found at the current position
+func (l *lexer) marker(inBlock bool) (itemType, string) {
+	cands := []markerItem{
+		{itemBlockBegin, l.mo.BlockBegin},
+		{itemBlockEnd, l.mo.BlockEnd},
+	}
+	if inBlock {
+		cands = append(cands,
+			markerItem{itemOptionsBegin, l.mo.OptionsBegin},
+			markerItem{itemOptionsEnd, l.mo.OptionsEnd},
+			markerItem{itemElementBegin, l.mo.ElementBegin},
+			markerItem{itemElementEnd, l.mo.ElementEnd},
+		)
+	}
+
+	typ, found := itemText, ""
+	rest := l.src[l.pos:]
+	for _, c := range cands {
+		if c.marker != "" && len(c.marker) > len(found) && strings.HasPrefix(rest, c.marker) {
+			typ, found = c.typ, c.marker
+		}
+	}
+	return typ, found
+}
+
+// nextText returns the next run of text or marker.  Outside of blocks only the
+// block markers are recognized.
+func (l *lexer) nextText(inBlock bool) item {
+	start := l.pos
+	for l.pos < len(l.src) {
+		typ, m := l.marker(inBlock)
+		if m == "" {
+			l.pos++
+			continue
+		}
+		if l.pos > start {
+			return item{itemText, Pos(start), l.src[start:l.pos]}
+		}
+		l.pos += len(m)
+		return item{typ, Pos(start), m}
+	}
+	if l.pos > start {
+		return item{itemText, Pos(start), l.src[start:l.pos]}
+	}
+	return item{itemEOF, Pos(l.pos), ""}
+}
+
+// nextList returns the next item of an options or element list which is
+// terminated by end.  An empty end means the list runs to the end of input.
+func (l *lexer) nextList(end string) item {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return item{itemEOF, Pos(start), ""}
+	}
+	if end != "" && strings.HasPrefix(l.src[l.pos:], end) {
+		l.pos += len(end)
+		return item{itemEnd, Pos(start), end}
+	}
+
+	switch c := l.src[l.pos]; c {
+	case '|':
+		l.pos++
+		return item{itemPipe, Pos(start), "|"}
+	case ':':
+		l.pos++
+		return item{itemColon, Pos(start), ":"}
+	case '"', '\'':
+		//quotes only have a meaning at the start of a word.  Nothing within
+		//them is escaped.
+		i := strings.IndexByte(l.src[l.pos+1:], c)
+		if i < 0 {
+			l.pos = len(l.src)
+			return item{itemError, Pos(start), "unterminated quoted string"}
+		}
+		l.pos += i + 2
+		return item{itemString, Pos(start), l.src[start+1 : l.pos-1]}
+	}
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '|' || c == ':' || unicode.IsSpace(rune(c)) {
+			break
+		}
+		if end != "" && strings.HasPrefix(l.src[l.pos:], end) {
+			break
+		}
+		l.pos++
+	}
+	return item{itemWord, Pos(start), l.src[start:l.pos]}
+}
+
+type parser struct {
+	lex *lexer
+}
+
+func newParser(s string, mo MarkerOptions) *parser {
+	return &parser{&lexer{src: s, mo: mo}}
+}
+
+// newParserAt parses s[start:end] while keeping positions relative to s.
+func newParserAt(s string, start, end int, mo MarkerOptions) *parser {
+	return &parser{&lexer{src: s[:end], pos: start, mo: mo}}
+}
+
+func (p *parser) errorf(kind error, pos Pos, marker, format string, args ...interface{}) error {
+	return locate(newParseError(kind, pos, marker, format, args...), p.lex.src)
+}
+
+// Parse parses an entire input into a Template.  Text outside of the blocks
+// is kept as is.
+func Parse(s string, mo MarkerOptions) (*Template, error) {
+	p := newParser(s, mo)
+	t := &Template{Source: s, Markers: mo}
+	for {
+		it := p.lex.nextText(false)
+		switch it.typ {
+		case itemEOF:
+			return t, nil
+		case itemText:
+			t.Nodes = append(t.Nodes, &TextNode{it.pos, it.val})
+		case itemBlockBegin:
+			b, err := p.parseBlock(it.pos, false)
+			if err != nil {
+				return nil, err
+			}
+			t.Nodes = append(t.Nodes, b)
+		case itemBlockEnd:
+			return nil, p.errorf(ErrUnbalancedBlock, it.pos, it.val, "block end marker (%s) found without a beginning marker (%s)", mo.BlockEnd, mo.BlockBegin)
+		}
+	}
+}
+
+// parseBody parses s[start:end] as the contents of a single block whose
+// enclosing block markers are outside of that range.
+func parseBody(s string, start, end int, mo MarkerOptions) (*BlockNode, error) {
+	return newParserAt(s, start, end, mo).parseBlock(Pos(start), true)
+}
+
+// parseBlock parses a block up to its end marker.  When body is set there is
+// no end marker and the block runs to the end of input.
+func (p *parser) parseBlock(pos Pos, body bool) (*BlockNode, error) {
+	//options can change the element markers for this block and its sub blocks
+	saved := p.lex.mo
+	defer func() { p.lex.mo = saved }()
+
+	mo := p.lex.mo
+	b := &BlockNode{Pos: pos}
+	for {
+		it := p.lex.nextText(true)
+		switch it.typ {
+		case itemEOF:
+			if body {
+				return b, nil
+			}
+			return nil, p.errorf(ErrUnbalancedBlock, pos, mo.BlockBegin, "no matching block end marker (%s) for block", mo.BlockEnd)
+		case itemText:
+			b.Body = append(b.Body, &TextNode{it.pos, it.val})
+		case itemBlockBegin:
+			sub, err := p.parseBlock(it.pos, false)
+			if err != nil {
+				return nil, err
+			}
+			b.Body = append(b.Body, sub)
+		case itemBlockEnd:
+			if body {
+				return nil, p.errorf(ErrUnbalancedBlock, it.pos, it.val, "block end marker (%s) found without a beginning marker (%s)", mo.BlockEnd, mo.BlockBegin)
+			}
+			return b, nil
+		case itemOptionsBegin:
+			if b.Options != nil {
+				return nil, p.errorf(ErrUnbalancedOptions, it.pos, it.val, "block has more than one options list")
+			}
+			opts, err := p.parseList(it, mo.OptionsEnd, ErrUnbalancedOptions)
+			if err != nil {
+				return nil, err
+			}
+			b.Options = &OptionsNode{it.pos, opts}
+			for _, o := range opts {
+				switch o.Key {
+				case "elementbegin":
+					p.lex.mo.ElementBegin = o.Value
+				case "elementend":
+					p.lex.mo.ElementEnd = o.Value
+				}
+			}
+		case itemOptionsEnd:
+			return nil, p.errorf(ErrUnbalancedOptions, it.pos, it.val, "end of options marker (%s) found without a beginning marker (%s)", mo.OptionsEnd, mo.OptionsBegin)
+		case itemElementBegin:
+			opts, err := p.parseList(it, p.lex.mo.ElementEnd, ErrUnbalancedElement)
+			if err != nil {
+				return nil, err
+			}
+			if len(opts) == 0 {
+				return nil, p.errorf(ErrUnknownElement, it.pos, it.val, "empty element")
+			}
+			b.Body = append(b.Body, elementNode(it.pos, opts))
+		case itemElementEnd:
+			return nil, p.errorf(ErrUnbalancedElement, it.pos, it.val, "element end marker (%s) found without a beginning marker (%s)", p.lex.mo.ElementEnd, p.lex.mo.ElementBegin)
+		}
+	}
+}
+
+// elementNode makes an element from its list.  "name as alias" names the
+// values of the element, and a value given with the name, as in "ref: fn", is
+// kept as an option with the same key as the name.
+func elementNode(pos Pos, opts []Option) *ElementNode {
+	el := &ElementNode{Pos: pos, Name: opts[0].Key, Options: opts[1:]}
+	if f := strings.Fields(el.Name); len(f) == 3 && f[1] == "as" {
+		el.Name, el.As = f[0], f[2]
+	}
+	if opts[0].Value != "" {
+		o := opts[0]
+		o.Key = el.Name
+		el.Options = append([]Option{o}, el.Options...)
+	}
+	return el
+}
+
+// parseList parses "key: value | flag | key: arg1: arg2" following the begin
+// marker open up to the end marker.  Errors are reported as kind.
+func (p *parser) parseList(open item, end string, kind error) ([]Option, error) {
+	var opts []Option
+
+	//the colon separated segments of the option being read
+	type segment struct {
+		start, end int
+		items      []item
+	}
+	var segs []segment
+
+	value := func(s segment) string {
+		if len(s.items) == 1 && s.items[0].typ == itemString {
+			return s.items[0].val
+		}
+		return strings.TrimSpace(p.lex.src[s.start:s.end])
+	}
+
+	finish := func() {
+		if len(segs) == 0 {
+			return
+		}
+		o := Option{Pos: Pos(segs[0].start), Key: strings.ToLower(value(segs[0]))}
+		if len(segs) > 1 {
+			last := segs[len(segs)-1]
+			if len(segs) == 2 {
+				o.Value = value(last)
+			} else {
+				o.Value = strings.TrimSpace(p.lex.src[segs[1].start:last.end])
+			}
+			for _, s := range segs[1:] {
+				o.Args = append(o.Args, value(s))
+			}
+		}
+		opts = append(opts, o)
+		segs = nil
+	}
+
+	for {
+		it := p.lex.nextList(end)
+		switch it.typ {
+		case itemError:
+			return nil, p.errorf(kind, it.pos, "", "%s", it.val)
+		case itemEOF:
+			if end != "" {
+				return nil, p.errorf(kind, open.pos, open.val, "no matching end marker (%s)", end)
+			}
+			finish()
+			return opts, nil
+		case itemEnd:
+			finish()
+			return opts, nil
+		case itemPipe:
+			finish()
+		case itemColon:
+			if len(segs) == 0 {
+				segs = append(segs, segment{start: int(it.pos), end: int(it.pos)})
+			}
+			segs = append(segs, segment{start: int(it.pos) + 1, end: int(it.pos) + 1})
+		case itemWord, itemString:
+			if len(segs) == 0 {
+				segs = append(segs, segment{start: int(it.pos)})
+			}
+			cur := &segs[len(segs)-1]
+			if len(cur.items) == 0 {
+				cur.start = int(it.pos)
+			}
+			cur.items = append(cur.items, it)
+			cur.end = p.lex.pos
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package datagen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	tmpl, err := Parse(" 12 {{{ [[[ count: 2 ]]] {{ country | regex: ^C.* }} {{{ {{ firstname }} }}} }}} 34 ", DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(tmpl.Nodes) != 3 {
+		t.Fatalf("FAIL. Expected 3 top level nodes. Received %d.", len(tmpl.Nodes))
+	}
+	if txt, ok := tmpl.Nodes[0].(*TextNode); !ok || txt.Text != " 12 " {
+		t.Errorf("FAIL. Expected text ' 12 '. Received %+v.", tmpl.Nodes[0])
+	}
+
+	b, ok := tmpl.Nodes[1].(*BlockNode)
+	if !ok {
+		t.Fatalf("FAIL. Expected a block. Received %+v.", tmpl.Nodes[1])
+	}
+	if b.Options == nil || len(b.Options.Options) != 1 || b.Options.Options[0].Key != "count" || b.Options.Options[0].Value != "2" {
+		t.Errorf("FAIL. Expected count: 2 as options. Received %+v.", b.Options)
+	}
+
+	var elements, blocks int
+	for _, n := range b.Body {
+		switch n := n.(type) {
+		case *ElementNode:
+			elements++
+			if n.Name != "country" || len(n.Options) != 1 || n.Options[0].Key != "regex" || n.Options[0].Value != "^C.*" {
+				t.Errorf("FAIL. Expected country | regex: ^C.*. Received %+v.", n)
+			}
+		case *BlockNode:
+			blocks++
+			if len(n.Body) != 3 {
+				t.Errorf("FAIL. Expected sub block body of 3 nodes. Received %+v.", n.Body)
+			}
+		}
+	}
+	if elements != 1 || blocks != 1 {
+		t.Errorf("FAIL. Expected 1 element and 1 sub block. Received %d and %d.", elements, blocks)
+	}
+}
+
+func Test_Parse_Markers(t *testing.T) {
+	for _, tc := range []struct {
+		s  string
+		mo MarkerOptions
+	}{
+		{"a {{{ [[[count:3]]] {{ country }} }}} b", DEFAULT},
+		{"a {{ [count:3] { country } }} b", CSV},
+		{"a {{ [[count:3]] { country } }} b", XML},
+		{"a $( $[count:3]$ ${ country }$ )$ b", DOLLAR},
+	} {
+		tmpl, err := Parse(tc.s, tc.mo)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.s, err)
+			continue
+		}
+		b, ok := tmpl.Nodes[1].(*BlockNode)
+		if !ok || b.Options == nil || b.Options.Options[0].Value != "3" {
+			t.Errorf("FAIL. Expected a block with count 3 for %s. Received %+v.", tc.s, tmpl.Nodes[1])
+			continue
+		}
+		if el, ok := b.Body[2].(*ElementNode); !ok || el.Name != "country" {
+			t.Errorf("FAIL. Expected country element for %s. Received %+v.", tc.s, b.Body[2])
+		}
+	}
+}
+
+func Test_Parse_Quotes(t *testing.T) {
+	s := `[[[ separator: "]]] | ," | lastseparator: '' ]]] {{ country | regex: "}}|{{" | replace: " ": "_" }}`
+	b, err := parseBody(s, 0, len(s), DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expOpts := []Option{
+		{Pos: 4, Key: "separator", Value: "]]] | ,", Args: []string{"]]] | ,"}},
+		{Pos: 27, Key: "lastseparator", Value: "", Args: []string{""}},
+	}
+	if !reflect.DeepEqual(b.Options.Options, expOpts) {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", expOpts, b.Options.Options)
+	}
+
+	el := b.Body[1].(*ElementNode)
+	if el.Options[0].Value != "}}|{{" {
+		t.Errorf("FAIL. Expected regex value }}|{{. Received %s.", el.Options[0].Value)
+	}
+	if !reflect.DeepEqual(el.Options[1].Args, []string{" ", "_"}) {
+		t.Errorf("FAIL. Expected replace args [' ' '_']. Received %q.", el.Options[1].Args)
+	}
+}
+
+func Test_Parse_ElementMarkerOverride(t *testing.T) {
+	s := `[[[ elementBegin: <% | elementEnd: %> ]]] <% country %> {{ country }}`
+	b, err := parseBody(s, 0, len(s), DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if el, ok := b.Body[1].(*ElementNode); !ok || el.Name != "country" {
+		t.Errorf("FAIL. Expected country element. Received %+v.", b.Body[1])
+	}
+	if txt, ok := b.Body[2].(*TextNode); !ok || txt.Text != " {{ country }}" {
+		t.Errorf("FAIL. Expected {{ country }} as text. Received %+v.", b.Body[2])
+	}
+}
+
+func Test_Parse_Malformed(t *testing.T) {
+	for _, s := range []string{
+		"{{{ {{ country }} ",
+		"{{{ {{ country }} }}} }}}",
+		"{{{ [[[ count: 3 {{ country }} }}}",
+		"{{{ count: 3 ]]] {{ country }} }}}",
+		"{{{ {{ country }}}",
+		"{{{ {{ country | regex: 'abc }} }}}",
+		"{{{ {{ }} }}}",
+	} {
+		if _, err := Parse(s, DEFAULT); err == nil {
+			t.Errorf("FAIL. Expected an error for %s.", s)
+		} else {
+			t.Logf("PASS. Received error for %s: %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package datagen
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// patternOptions are the options of the pattern element, e.g.
+// {{ pattern | regex: "[A-Z]{3}-\d{4}" | maxrepeat: 5 }}
+type patternOptions struct {
+	Regex     string
+	MaxRepeat int //the most repeats for *, + and {n,}
+}
+
+// patternElement gives random strings which match a regular expression.
+// Anchors and word boundaries are ignored.
+type patternElement struct{}
+
+type patternState struct {
+	opts patternOptions
+	re   *syntax.Regexp
+}
+
+func (patternElement) Generate(ctx *Context) (string, error) {
+	st, ok := ctx.State.(*patternState)
+	if !ok {
+		st = &patternState{opts: patternOptions{MaxRepeat: 10}}
+		if err := ctx.Decode(&st.opts); err != nil {
+			return "", err
+		}
+		if st.opts.Regex == "" {
+			return "", ctx.OptionError("regex", "a regex is needed")
+		}
+		if st.opts.MaxRepeat < 0 {
+			return "", ctx.OptionError("maxrepeat", "maxrepeat can not be negative")
+		}
+		re, err := syntax.Parse(st.opts.Regex, syntax.Perl)
+		if err != nil {
+			return "", ctx.OptionError("regex", "%v", err)
+		}
+		if err := checkPattern(re); err != nil {
+			return "", ctx.OptionError("regex", "%v", err)
+		}
+		st.re = re
+		ctx.State = st
+	}
+
+	var sb strings.Builder
+	writePattern(&sb, ctx.Rand, st.re, st.opts.MaxRepeat)
+	return sb.String(), nil
+}
+
+// checkPattern reports the parts of a regex which nothing can match
+func checkPattern(re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return fmt.Errorf("%s can not match anything", re)
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return fmt.Errorf("%s can not match anything", re)
+		}
+	}
+	for _, sub := range re.Sub {
+		if err := checkPattern(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printable are the characters used for . and for classes that include them
+var printable = []rune{' ', '~'}
+
+func writePattern(sb *strings.Builder, r *rand.Rand, re *syntax.Regexp, maxRepeat int) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, c := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && r.Intn(2) == 0 {
+				c = unicode.SimpleFold(c)
+			}
+			sb.WriteRune(c)
+		}
+	case syntax.OpCharClass:
+		sb.WriteRune(randomRune(r, re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteRune(randomRune(r, printable))
+	case syntax.OpCapture:
+		writePattern(sb, r, re.Sub[0], maxRepeat)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writePattern(sb, r, sub, maxRepeat)
+		}
+	case syntax.OpAlternate:
+		writePattern(sb, r, re.Sub[r.Intn(len(re.Sub))], maxRepeat)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatRange(re, maxRepeat)
+		n := min + r.Intn(max-min+1)
+		for i := 0; i < n; i++ {
+			writePattern(sb, r, re.Sub[0], maxRepeat)
+		}
+	}
+	//anchors, word boundaries and empty matches give nothing
+}
+
+// repeatRange gives the number of repeats allowed, with no more than
+// maxRepeat more than the minimum for open ended repeats.
+func repeatRange(re *syntax.Regexp, maxRepeat int) (int, int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, maxRepeat
+	case syntax.OpPlus:
+		return 1, 1 + maxRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	}
+	if re.Max < 0 {
+		return re.Min, re.Min + maxRepeat
+	}
+	return re.Min, re.Max
+}
+
+// randomRune picks a rune from the ranges of a class.  Classes which include
+// printable ASCII characters, like \D or [^,], are kept to those so that the
+// output is readable.
+func randomRune(r *rand.Rand, ranges []rune) rune {
+	if ascii := intersectRanges(ranges, printable); len(ascii) > 0 {
+		ranges = ascii
+	}
+	total := 0
+	for i := 0; i < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	n := r.Intn(total)
+	for i := 0; i < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+		n -= size
+	}
+	return ranges[0]
+}
+
+// intersectRanges gives the parts of the ranges a which are within the single
+// range b.
+func intersectRanges(a, b []rune) []rune {
+	var out []rune
+	for i := 0; i < len(a); i += 2 {
+		lo, hi := a[i], a[i+1]
+		if lo < b[0] {
+			lo = b[0]
+		}
+		if hi > b[1] {
+			hi = b[1]
+		}
+		if lo <= hi {
+			out = append(out, lo, hi)
+		}
+	}
+	return out
+}
+
+func (patternElement) OptionNames() []string {
+	return optionNames(patternOptions{})
+}
+
+func init() {
+	Register("pattern", patternElement{})
+}
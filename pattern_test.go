@@ -0,0 +1,62 @@
+package datagen
+
+import (
+	"regexp"
+	"testing"
+)
+
+func Test_PatternElement(t *testing.T) {
+	for _, tc := range []struct {
+		eb    string
+		regex string
+	}{
+		{`pattern | regex: "[A-Z]{3}-\d{4}"`, `^[A-Z]{3}-\d{4}$`},
+		{`pattern | regex: "^(KA|TN|MH)-\d{2}-[A-Z]{1,2}-\d{4}$"`, `^(KA|TN|MH)-\d{2}-[A-Z]{1,2}-\d{4}$`},
+		{`pattern | regex: "SKU[0-9a-f]+x*"`, `^SKU[0-9a-f]+x*$`},
+		{`pattern | regex: "(?i)abc[^,\s]?"`, `^(?i)abc[^,\s]?$`},
+		{`pattern | regex: "\w+@\w+\.(com|org)"`, `^\w+@\w+\.(com|org)$`},
+		{`pattern | regex: "a.{2,}"`, `^a.{2,}$`},
+	} {
+		s, err := NewGenerator(1).GenElement(tc.eb, 50)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		re := regexp.MustCompile(tc.regex)
+		for _, v := range s {
+			if !re.MatchString(v) {
+				t.Errorf("FAIL. Expected a match of %s for %s. Received %q.", tc.regex, tc.eb, v)
+				break
+			}
+		}
+		t.Logf("PASS. Received %v for %s.", s[:5], tc.eb)
+	}
+}
+
+func Test_PatternElement_MaxRepeat(t *testing.T) {
+	s, err := NewGenerator(1).GenElement(`pattern | regex: "a+b*" | maxrepeat: 3`, 200)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	re := regexp.MustCompile(`^a{1,4}b{0,3}$`)
+	long := false
+	for _, v := range s {
+		if !re.MatchString(v) {
+			t.Errorf("FAIL. Expected at most 3 extra repeats. Received %q.", v)
+			break
+		}
+		long = long || v == "aaaabbb"
+	}
+	if !long {
+		t.Errorf("FAIL. Expected the longest string to be given at times. Received %v.", s)
+	}
+}
+
+func Test_PatternElement_BadOptions(t *testing.T) {
+	expectBadOption(t,
+		"pattern",
+		`pattern | regex: "[a-"`,
+		`pattern | regex: "[^\x00-\x{10FFFF}]"`,
+		`pattern | regex: "a*" | maxrepeat: -1`,
+	)
+}
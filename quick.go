@@ -0,0 +1,396 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Shrinker is implemented by elements whose values can be made simpler, to
+// find the simplest failing input of a property in Check.  Shrink gives
+// values simpler than v, the simplest first, which the element could have
+// given with the options of ctx.  ctx has been used to generate values, so
+// its State is set.
+type Shrinker interface {
+	Shrink(ctx *Context, v string) []string
+}
+
+// quickArg generates the values of one argument of a function.
+type quickArg struct {
+	typ reflect.Type
+	eg  ElementGenerator
+	ctx *Context
+}
+
+func (qa *quickArg) next(r *rand.Rand) (reflect.Value, string, error) {
+	qa.ctx.Rand = r
+	s, err := qa.eg.Generate(qa.ctx)
+	qa.ctx.Row++
+	if err != nil {
+		return reflect.Value{}, "", err
+	}
+	v := reflect.New(qa.typ).Elem()
+	if err := setString(v, s); err != nil {
+		return reflect.Value{}, "", fmt.Errorf("datagen: element %s: %w", qa.ctx.Name, err)
+	}
+	return v, s, nil
+}
+
+// quickArgs prepares the elements for the arguments of the function f.
+func (g *Generator) quickArgs(f interface{}, count int, elements []string) (reflect.Value, []*quickArg, error) {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func {
+		return fv, nil, errors.New("datagen: not a function")
+	}
+	ft := fv.Type()
+	if ft.NumIn() != len(elements) {
+		return fv, nil, fmt.Errorf("datagen: %d elements for a function of %d arguments", len(elements), ft.NumIn())
+	}
+
+	args := make([]*quickArg, len(elements))
+	for i, eb := range elements {
+		typ := ft.In(i)
+		if typ != timeType && !isBasic(typ.Kind()) {
+			return fv, nil, fmt.Errorf("datagen: argument %d: can not generate a %s", i+1, typ)
+		}
+		opts, err := newParser(eb, DEFAULT).parseList(item{}, "", ErrUnbalancedElement)
+		if err == nil && len(opts) == 0 {
+			err = newParseError(ErrUnknownElement, 0, "", "empty element")
+		}
+		if err != nil {
+			return fv, nil, locate(err, eb)
+		}
+		el := elementNode(opts[0].Pos, opts)
+		if typ == timeType {
+			g.timeValues(el)
+		}
+		eg, ctx, err := g.newContext(el, count)
+		if err != nil {
+			return fv, nil, locate(err, eb)
+		}
+		args[i] = &quickArg{typ, eg, ctx}
+	}
+	return fv, args, nil
+}
+
+// QuickValues gives a function for the Values of a quick.Config which
+// generates the arguments of f from the elements, one for each argument, e.g.
+//
+//	values, err := datagen.QuickValues(func(name string, age int) bool { ... },
+//		"firstname | regex: ^A", "int | min: 18 | max: 90")
+//	err = quick.Check(f, &quick.Config{Values: values})
+//
+// The random numbers given by testing/quick are used.
+func QuickValues(f interface{}, elements ...string) (func([]reflect.Value, *rand.Rand), error) {
+	return defaultGenerator().QuickValues(f, elements...)
+}
+
+func (g *Generator) QuickValues(f interface{}, elements ...string) (func([]reflect.Value, *rand.Rand), error) {
+	_, args, err := g.begin().quickArgs(f, 0, elements)
+	if err != nil {
+		return nil, err
+	}
+	return func(values []reflect.Value, r *rand.Rand) {
+		for i, qa := range args {
+			v, _, err := qa.next(r)
+			if err != nil {
+				//quick has no way to report errors, and a zero value
+				//would hide the problem
+				panic(err)
+			}
+			values[i] = v
+		}
+	}, nil
+}
+
+// QuickValue returns a value of the same type as v, filled as by Fill with
+// random numbers from r.  It makes it easy for a struct with datagen tags to
+// be a quick.Generator:
+//
+//	func (Person) Generate(r *rand.Rand, size int) reflect.Value {
+//		return datagen.QuickValue(Person{}, r)
+//	}
+func QuickValue(v interface{}, r *rand.Rand) reflect.Value {
+	p := reflect.New(reflect.TypeOf(v))
+	g := &Generator{rand: r, elements: newRegistry(nil), dicts: newDictCache()}
+	if err := g.Fill(p.Interface()); err != nil {
+		panic(err)
+	}
+	return p.Elem()
+}
+
+// Firstname is a first name from the bundled dataset.  It is a
+// quick.Generator.
+type Firstname string
+
+func (Firstname) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Firstname(quickElement(r, "firstname | random")))
+}
+
+// Country is a country from the bundled dataset.  It is a quick.Generator.
+type Country string
+
+func (Country) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Country(quickElement(r, "country | random")))
+}
+
+// Text is a string of letters.  It is a quick.Generator whose strings are no
+// longer than the size given by testing/quick.
+type Text string
+
+func (Text) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Text(randomText(r, 0, size, []rune(letters))))
+}
+
+func quickElement(r *rand.Rand, eb string) string {
+	g := &Generator{rand: r, elements: newRegistry(nil), dicts: newDictCache()}
+	s, err := g.GenElement(eb, 1)
+	if err != nil || len(s) == 0 {
+		panic(fmt.Sprintf("datagen: %s: %v", eb, err))
+	}
+	return s[0]
+}
+
+// CheckError is returned by Check for a property which does not hold.  In
+// are the arguments it first failed with, and Shrunk the simplest arguments
+// found which still fail.
+type CheckError struct {
+	Count  int   //the number of the call which failed, from 1
+	Seed   int64 //the seed of the generator, if Check chose it
+	In     []string
+	Shrunk []string
+	Err    error //returned by the property, if any
+}
+
+func (e *CheckError) Error() string {
+	s := fmt.Sprintf("datagen: #%d: failed on %q, shrunk to %q", e.Count, e.In, e.Shrunk)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	if e.Seed != 0 {
+		s += fmt.Sprintf(" (seed %d)", e.Seed)
+	}
+	return s
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// maxShrinks is the most calls of a property made while shrinking
+const maxShrinks = 1000
+
+// Check calls the property f count times with arguments generated from the
+// elements, one for each argument.  f returns a bool, which is false when
+// the property does not hold, or an error.  When it fails, the arguments
+// are shrunk towards simpler values, e.g. shorter text or earlier dictionary
+// lines, for elements which are a Shrinker, and a *CheckError is returned.
+func Check(f interface{}, count int, elements ...string) error {
+	seed := rand.Int63()
+	err := NewGenerator(seed).Check(f, count, elements...)
+	var ce *CheckError
+	if errors.As(err, &ce) {
+		ce.Seed = seed
+	}
+	return err
+}
+
+func (g *Generator) Check(f interface{}, count int, elements ...string) error {
+	fv, args, err := g.begin().quickArgs(f, count, elements)
+	if err != nil {
+		return err
+	}
+	ft := fv.Type()
+	if ft.NumOut() != 1 || (ft.Out(0).Kind() != reflect.Bool && ft.Out(0) != reflect.TypeOf((*error)(nil)).Elem()) {
+		return errors.New("datagen: the property must return a bool or an error")
+	}
+
+	//call gives nil if the property holds for the values
+	call := func(in []string) (failed bool, err error) {
+		vals := make([]reflect.Value, len(in))
+		for i, s := range in {
+			vals[i] = reflect.New(args[i].typ).Elem()
+			if err := setString(vals[i], s); err != nil {
+				return false, nil
+			}
+		}
+		out := fv.Call(vals)[0]
+		if out.Kind() == reflect.Bool {
+			return !out.Bool(), nil
+		}
+		if out.IsNil() {
+			return false, nil
+		}
+		return true, out.Interface().(error)
+	}
+
+	in := make([]string, len(args))
+	for n := 1; n <= count; n++ {
+		for i, qa := range args {
+			if _, in[i], err = qa.next(g.rand); err != nil {
+				return err
+			}
+		}
+		failed, ferr := call(in)
+		if !failed {
+			continue
+		}
+
+		ce := &CheckError{Count: n, In: append([]string(nil), in...), Err: ferr}
+		ce.Shrunk, ce.Err = shrink(args, in, call, ferr)
+		return ce
+	}
+	return nil
+}
+
+// shrink replaces the arguments with simpler ones for as long as the
+// property still fails.
+func shrink(args []*quickArg, in []string, call func([]string) (bool, error), ferr error) ([]string, error) {
+	cur := append([]string(nil), in...)
+	tries := 0
+	for changed := true; changed && tries < maxShrinks; {
+		changed = false
+		for i, qa := range args {
+			sh, ok := qa.eg.(Shrinker)
+			if !ok {
+				continue
+			}
+			for _, c := range sh.Shrink(qa.ctx, cur[i]) {
+				if tries++; tries > maxShrinks {
+					break
+				}
+				try := append([]string(nil), cur...)
+				try[i] = c
+				if failed, err := call(try); failed {
+					cur, ferr, changed = try, err, true
+					break
+				}
+			}
+		}
+	}
+	return cur, ferr
+}
+
+// Shrink gives shorter text, down to the minimum size, and then text of only
+// the first character of the charset.
+func (textElement) Shrink(ctx *Context, v string) []string {
+	st, ok := ctx.State.(*textState)
+	if !ok {
+		return nil
+	}
+	rs := []rune(v)
+	var out []string
+	for _, n := range []int{st.opts.MinSize, (st.opts.MinSize + len(rs)) / 2, len(rs) - 1} {
+		if n >= st.opts.MinSize && n < len(rs) {
+			out = append(out, string(rs[:n]))
+		}
+	}
+	if simple := strings.Repeat(string(st.chars[0]), len(rs)); simple != v {
+		out = append(out, simple)
+	}
+	return dedupe(out)
+}
+
+// Shrink gives earlier lines of the dictionary.
+func (dictElement) Shrink(ctx *Context, v string) []string {
+	st, ok := ctx.State.(*dictState)
+	if !ok {
+		return nil
+	}
+	i := 0
+	for i < len(st.lines) && st.lines[i] != v {
+		i++
+	}
+	var out []string
+	for _, j := range []int{0, i / 2, i - 1} {
+		if j >= 0 && j < i {
+			out = append(out, st.lines[j])
+		}
+	}
+	return dedupe(out)
+}
+
+// Shrink shrinks the value the element gave before the filters, and gives
+// the shrunk values filtered again.
+func (fe *filteredElement) Shrink(ctx *Context, v string) []string {
+	sh, ok := fe.eg.(Shrinker)
+	if !ok {
+		return nil
+	}
+	raw, ok := fe.raws[v]
+	if !ok {
+		if fe.last[0] != v {
+			return nil
+		}
+		raw = fe.last[1]
+	}
+	if fe.raws == nil {
+		fe.raws = make(map[string]string)
+	}
+	var out []string
+	for _, c := range sh.Shrink(ctx, raw) {
+		f, err := fe.apply(c)
+		if err != nil || f == v {
+			continue
+		}
+		if _, ok := fe.raws[f]; !ok {
+			fe.raws[f] = c
+		}
+		out = append(out, f)
+	}
+	return dedupe(out)
+}
+
+// Shrink gives the shrunk values of the element.  Each is a value on its
+// own, so they need not be new.
+func (u *uniqueElement) Shrink(ctx *Context, v string) []string {
+	if sh, ok := u.eg.(Shrinker); ok {
+		return sh.Shrink(ctx, v)
+	}
+	return nil
+}
+
+// Shrink gives numbers closer to the minimum.
+func (ne numberElement) Shrink(ctx *Context, v string) []string {
+	st, ok := ctx.State.(*numberState)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil
+	}
+	o := st.opts
+	var out []string
+	for _, c := range []float64{o.Min, o.Min + (n-o.Min)/2, n - math.Max(o.Step, 1)} {
+		if o.Step > 0 {
+			c = o.Min + math.Floor((c-o.Min)/o.Step)*o.Step
+		}
+		if c < o.Min || c >= n {
+			continue
+		}
+		if ne.isInt {
+			out = append(out, strconv.FormatInt(int64(math.Round(c)), 10))
+		} else {
+			out = append(out, strconv.FormatFloat(c, 'f', o.Precision, 64))
+		}
+	}
+	return dedupe(out)
+}
+
+// dedupe removes repeated values, keeping the order.
+func dedupe(values []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func Test_QuickValues(t *testing.T) {
+	f := func(name string, age int) bool {
+		return strings.HasPrefix(name, "A") && age >= 18 && age <= 90
+	}
+	values, err := NewGenerator(1).QuickValues(f, "firstname | regex: ^A | random", "int | min: 18 | max: 90")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := quick.Check(f, &quick.Config{Values: values, Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Errorf("FAIL. Expected the property to hold. Received %v.", err)
+	} else {
+		t.Logf("PASS. The property holds for generated values.")
+	}
+
+	if _, err := QuickValues(f, "firstname"); err == nil {
+		t.Errorf("FAIL. Expected an error for too few elements.")
+	}
+	if _, err := QuickValues(f, "firstname", "cuntry"); !errors.Is(err, ErrUnknownElement) {
+		t.Errorf("FAIL. Expected %v. Received %v.", ErrUnknownElement, err)
+	}
+}
+
+type quickPerson struct {
+	Name string `datagen:"firstname | random"`
+	Age  int    `datagen:"int | min: 18 | max: 90"`
+}
+
+func (quickPerson) Generate(r *rand.Rand, size int) reflect.Value {
+	return QuickValue(quickPerson{}, r)
+}
+
+func Test_QuickGenerator(t *testing.T) {
+	f := func(c Country, n Firstname, s Text, p quickPerson) bool {
+		return c != "" && n != "" && strings.ToUpper(string(n)) == string(n) && len(s) <= 50 && p.Name != "" && p.Age >= 18
+	}
+	if err := quick.Check(f, &quick.Config{MaxCountScale: 0.5, Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Errorf("FAIL. Expected the property to hold. Received %v.", err)
+	} else {
+		t.Logf("PASS. The property holds for generated values.")
+	}
+}
+
+func Test_Check_Shrink(t *testing.T) {
+	for _, tc := range []struct {
+		f       interface{}
+		element string
+		exp     string
+	}{
+		{func(s string) bool { return len(s) < 8 }, "text | minsize: 2 | maxsize: 30", "aaaaaaaa"},
+		{func(c string) bool { return c < "China" }, "country | random", "China"},
+		{func(n int) bool { return n < 50 }, "int | min: 1 | max: 100", "50"},
+		//filtered and unique values are shrunk before the filters
+		{func(s string) bool { return len(s) < 8 }, "text | minsize: 2 | maxsize: 30 | upper", "AAAAAAAA"},
+		{func(c string) bool { return c < "CHINA" }, "country | random | upper", "CHINA"},
+		{func(n int) bool { return n < 50 }, "int | min: 1 | max: 100 | unique", "50"},
+		{func(n float64) bool { return n < 2.5 }, "float | min: 0 | max: 10 | step: 0.5 | precision: 1", "2.5"},
+		//without a shrinker the value is kept
+		{func(n int) bool { return n < 5 }, "seq | start: 9", "9"},
+	} {
+		err := NewGenerator(1).Check(tc.f, 200, tc.element)
+		var ce *CheckError
+		if !errors.As(err, &ce) {
+			t.Errorf("FAIL. Expected a *CheckError for %s. Received %v.", tc.element, err)
+			continue
+		}
+		if len(ce.Shrunk) != 1 || ce.Shrunk[0] != tc.exp {
+			t.Errorf("FAIL. Expected %s to shrink to %q. Received %v.", tc.element, tc.exp, err)
+		} else {
+			t.Logf("PASS. Expected %s to shrink to %q. Received %v.", tc.element, tc.exp, err)
+		}
+	}
+}
+
+func Test_Check_Errors(t *testing.T) {
+	errTooLong := errors.New("too long")
+	err := Check(func(a, b string) error {
+		if len(a)+len(b) > 12 {
+			return fmt.Errorf("%w: %d", errTooLong, len(a)+len(b))
+		}
+		return nil
+	}, 100, "firstname | random", "country | random")
+
+	var ce *CheckError
+	if !errors.As(err, &ce) || !errors.Is(err, errTooLong) || ce.Seed == 0 {
+		t.Fatalf("FAIL. Expected a *CheckError with a seed. Received %v.", err)
+	}
+	//the seed gives the same failure again
+	err = NewGenerator(ce.Seed).Check(func(a, b string) error {
+		if len(a)+len(b) > 12 {
+			return errTooLong
+		}
+		return nil
+	}, 100, "firstname | random", "country | random")
+	var again *CheckError
+	if !errors.As(err, &again) || again.Count != ce.Count || strings.Join(again.In, ",") != strings.Join(ce.In, ",") {
+		t.Errorf("FAIL. Expected the same failure from seed %d. Received %v and %v.", ce.Seed, ce, err)
+	} else {
+		t.Logf("PASS. Received %v.", ce)
+	}
+
+	if err := Check(func(s string) bool { return true }, 10, "country"); err != nil {
+		t.Errorf("FAIL. Expected the property to hold. Received %v.", err)
+	}
+	if err := Check(func(s string) {}, 10, "country"); err == nil {
+		t.Errorf("FAIL. Expected an error for a property without a result.")
+	}
+}
@@ -0,0 +1,167 @@
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type valueState uint8
+
+const (
+	notGenerated valueState = iota
+	generating
+	generated
+)
+
+// rowScope holds the values of the elements of one row of a block, so that
+// elements named with "as" can be referred to by the others.  Values are
+// generated when first needed, so an element can refer to one that comes
+// after it.
+type rowScope struct {
+	parent *rowScope
+	names  map[string]int //index of the named elements in the body
+	row    int
+	values []string
+	state  []valueState
+	gen    func(i int) (string, error)
+}
+
+func (rs *rowScope) reset(row int) {
+	rs.row = row
+	for i := range rs.state {
+		rs.state[i] = notGenerated
+	}
+}
+
+// value gives the value of the element at i in the body for the current row.
+func (rs *rowScope) value(i int) (string, error) {
+	switch rs.state[i] {
+	case generated:
+		return rs.values[i], nil
+	case generating:
+		return "", fmt.Errorf("the element refers to itself")
+	}
+	rs.state[i] = generating
+	v, err := rs.gen(i)
+	if err != nil {
+		rs.state[i] = notGenerated
+		return "", err
+	}
+	rs.values[i], rs.state[i] = v, generated
+	return v, nil
+}
+
+// lookup finds the value of the named element in this row, or in the rows of
+// the enclosing blocks.
+func (rs *rowScope) lookup(name string) (string, error) {
+	for s := rs; s != nil; s = s.parent {
+		if i, ok := s.names[name]; ok {
+			return s.value(i)
+		}
+	}
+	return "", fmt.Errorf("no element named %q", name)
+}
+
+// Lookup returns the value in the current row of the element named with
+// "as", e.g. {{ firstname as fn }}.  Elements of enclosing blocks can be
+// looked up too, with the values of their current rows.
+func (ctx *Context) Lookup(name string) (string, error) {
+	if ctx.row == nil {
+		return "", fmt.Errorf("no element named %q outside of a block", name)
+	}
+	return ctx.row.lookup(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// refError reports an error looking up the value of an element at the option
+// with the key.  Errors of the element looked up are given as they are.
+func refError(ctx *Context, key string, err error) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return ctx.OptionError(key, "%v", err)
+}
+
+// refElement gives the value of a named element, e.g. {{ ref: fn }}.
+type refElement struct{}
+
+func (refElement) Generate(ctx *Context) (string, error) {
+	name, _ := ctx.Option("ref")
+	if name == "" {
+		return "", ctx.OptionError("ref", "expected ref: name")
+	}
+	v, err := ctx.Lookup(name)
+	if err != nil {
+		return "", refError(ctx, "ref", err)
+	}
+	return v, nil
+}
+
+// emailOptions are the options of the email element, e.g.
+// {{ email | from: fn: ln | domain: "example.com,example.org" }}
+type emailOptions struct {
+	From   string //names of elements the user part is made from
+	Domain string //comma separated domains to choose from
+	Sep    string //between the parts of From
+}
+
+var emailDomains = []string{"example.com", "example.net", "example.org"}
+
+// emailElement gives email addresses.  The user part is made from the values
+// of the elements named in from, or is random letters.
+type emailElement struct{}
+
+func (emailElement) Generate(ctx *Context) (string, error) {
+	o := emailOptions{Sep: "."}
+	if err := ctx.Decode(&o); err != nil {
+		return "", err
+	}
+
+	var user []string
+	if o.From != "" {
+		for _, name := range strings.Split(o.From, ":") {
+			v, err := ctx.Lookup(name)
+			if err != nil {
+				return "", refError(ctx, "from", err)
+			}
+			if v = emailPart(v); v != "" {
+				user = append(user, v)
+			}
+		}
+	}
+	if len(user) == 0 {
+		user = append(user, randomText(ctx.Rand, 5, 10, []rune(smallLetters)))
+	}
+
+	domains := emailDomains
+	if o.Domain != "" {
+		domains = strings.Split(o.Domain, ",")
+	}
+	domain := strings.TrimSpace(domains[ctx.Rand.Intn(len(domains))])
+	return strings.Join(user, o.Sep) + "@" + domain, nil
+}
+
+// emailPart lower cases s and keeps only the letters and digits.
+func emailPart(s string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+func (refElement) OptionNames() []string {
+	return []string{"ref"}
+}
+
+func (emailElement) OptionNames() []string {
+	return optionNames(emailOptions{})
+}
+
+func init() {
+	Register("ref", refElement{})
+	Register("email", emailElement{})
+}
@@ -0,0 +1,77 @@
+package datagen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_ElementNode_As(t *testing.T) {
+	tmpl, err := Parse("{{{ {{ firstname as fn | random }} {{ ref: fn }} }}}", DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body := tmpl.Nodes[0].(*BlockNode).Body
+	el := body[1].(*ElementNode)
+	if el.Name != "firstname" || el.As != "fn" || len(el.Options) != 1 || el.Options[0].Key != "random" {
+		t.Errorf("FAIL. Expected firstname as fn with random. Received %+v.", el)
+	}
+	el = body[3].(*ElementNode)
+	if el.Name != "ref" || len(el.Options) != 1 || el.Options[0].Key != "ref" || el.Options[0].Value != "fn" {
+		t.Errorf("FAIL. Expected ref with the option ref: fn. Received %+v.", el)
+	}
+}
+
+func Test_Ref_SameRow(t *testing.T) {
+	s := "{{{ [[[ count: 3 | separator: ';' ]]] {{ firstname as fn | random }},{{ ref:fn }},{{ email | from:fn | domain: example.com }} }}}"
+	gen, err := NewGenerator(1).GenBlock(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rows := strings.Split(strings.TrimSpace(gen), ";")
+	if len(rows) != 3 {
+		t.Fatalf("FAIL. Expected 3 rows. Received %s.", gen)
+	}
+	for _, row := range rows {
+		f := strings.Split(row, ",")
+		if len(f) != 3 || f[0] != f[1] || f[2] != emailPart(f[0])+"@example.com" {
+			t.Errorf("FAIL. Expected the name, the same name and its email. Received %s.", row)
+		} else {
+			t.Logf("PASS. Received %s.", row)
+		}
+	}
+}
+
+func Test_Ref_ForwardAndNested(t *testing.T) {
+	//the email comes before the names it is made from, and the sub block
+	//refers to the row of its enclosing block
+	s := "{{{ [[[ count: 2 | separator: ';' ]]] {{ email | from: fn: ln | domain: x.org }} {{ firstname as fn }} {{ country as ln }} [{{{ [[[ count: 2 | separator: ',' | lastseparator: '' ]]] {{ ref: ln }} }}}] }}}"
+	gen, err := NewGenerator(1).GenBlock(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "aaron.afghanistan@x.org AARON Afghanistan [Afghanistan,Afghanistan];abdul.albania@x.org ABDUL Albania [Albania,Albania]\n"
+	if gen != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, gen)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, gen)
+	}
+}
+
+func Test_Ref_Errors(t *testing.T) {
+	for _, s := range []string{
+		"{{{ {{ ref: nobody }} }}}",
+		"{{{ {{ ref }} }}}",
+		"{{{ {{ ref as a | ref: a }} }}}",
+		"{{{ {{ email as a | from: b }} {{ ref as b | ref: a }} }}}",
+		"{{{ {{ country as c }} {{ firstname as c }} }}}",
+		"{{{ {{{ {{ country as c }} }}} {{ ref: c }} }}}",
+	} {
+		_, err := NewGenerator(1).GenBlock(s)
+		if !errors.Is(err, ErrBadOptionValue) {
+			t.Errorf("FAIL. Expected %v for %s. Received %v.", ErrBadOptionValue, s, err)
+		} else {
+			t.Logf("PASS. Expected %v for %s. Received %v.", ErrBadOptionValue, s, err)
+		}
+	}
+}
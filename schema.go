@@ -0,0 +1,806 @@
+package datagen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema describes data as a list of fields instead of a template, which is
+// easier to read for wide records.  It is usually read from a JSON or YAML
+// file with LoadSchema, e.g.
+//
+//	name: people
+//	count: 100
+//	format: csv
+//	fields:
+//	  - name: id
+//	    type: seq
+//	  - name: first
+//	    type: firstname
+//	    options: {random: true}
+//	    filters: [title]
+//	  - name: email
+//	    type: email
+//	    options: {from: first}
+//
+// gives the same values as the template
+//
+//	{{{ [[[ count: 100 ]]] {{ seq as id }},{{ firstname as first | random | title }},{{ email | from: first }} }}}
+//
+// with a header line, and with the values escaped for the format.
+//
+// Related tables are given as a list of tables instead, where fields can
+// refer to the values of another table; see Field.
+type Schema struct {
+	Table
+
+	//Tables are given instead of the fields of a single table.  They are
+	//generated and written one after another, each after the tables it
+	//refers to.  Name is then the enclosing element of the xml format.
+	Tables []Table `json:"tables"`
+
+	//Format is csv (the default), json, jsonl, xml or sql
+	Format string `json:"format"`
+
+	//Seed gives the same data every time, as the seed option of a block does
+	Seed *int64 `json:"seed"`
+
+	//Dictionaries are registered for the schema only, as the dictionary
+	//option of a block does.  Relative file names are relative to the
+	//schema file.
+	Dictionaries map[string]FileList `json:"dictionaries"`
+
+	dir string //directory of the schema file, if any
+}
+
+// Table is Count rows of the Fields.  Name is used by the xml and sql
+// formats, as the enclosing element and the table inserted into, and by the
+// fields of other tables to refer to its fields.
+type Table struct {
+	Name   string  `json:"name"`
+	Count  int     `json:"count"` //1 if not given
+	Fields []Field `json:"fields"`
+}
+
+// Field is one value of each row.  Type is an element type like firstname or
+// int.  Options are the options of the element: true is a flag like random,
+// and a list gives the parts of an option like from: first: last.  Filters
+// are written as they are in a template, e.g. "truncate: 5".  Other fields
+// can refer to the values of the field by its Name, as with "as" in a
+// template.
+//
+// A field with Ref instead of Type gives the values of a field of another
+// table, e.g. customers.id, so that they are keys which exist.  The values
+// are picked at random, or with Per each row of the other table is given
+// Per.Min to Per.Max rows of this table in turn, and the count of the table
+// is the total.  Null is the probability of a value being null, which is
+// empty in csv, null in json, NULL in sql and a missing element in xml.
+type Field struct {
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Options map[string]interface{} `json:"options"`
+	Filters []string               `json:"filters"`
+
+	Ref  string  `json:"ref"`
+	Per  *Range  `json:"per"`
+	Null float64 `json:"null"`
+}
+
+// FileList is one or more file names.  In a schema file it can be given as a
+// single name or as a list.
+type FileList []string
+
+func (fl *FileList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*fl = FileList{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(fl))
+}
+
+// ParseSchema reads a schema in JSON, or in YAML if it does not start with
+// {.  Only the common part of YAML is understood: mappings and lists by
+// indentation, flow lists and mappings like [a, b] and {random: true},
+// quoted and plain values, and comments.
+func ParseSchema(data []byte) (*Schema, error) {
+	if t := bytes.TrimSpace(data); len(t) == 0 || t[0] != '{' {
+		v, err := parseYAML(data)
+		if err != nil {
+			ye := err.(*yamlError)
+			return nil, &SchemaError{Path: fmt.Sprintf("line %d", ye.Line), Msg: ye.Msg, Err: ErrBadSchema}
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, &SchemaError{Msg: err.Error(), Err: ErrBadSchema}
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	var s Schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, decodeError(err, data)
+	}
+	return &s, nil
+}
+
+// decodeError reports an error decoding a schema with where it is
+func decodeError(err error, data []byte) error {
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &se):
+		line := bytes.Count(data[:se.Offset], []byte("\n")) + 1
+		return &SchemaError{Path: fmt.Sprintf("line %d", line), Msg: strings.TrimPrefix(se.Error(), "json: "), Err: ErrBadSchema}
+	case errors.As(err, &te):
+		return &SchemaError{Path: te.Field, Msg: fmt.Sprintf("expected %s, received %s", te.Type, te.Value), Err: ErrBadSchema}
+	case err == io.EOF:
+		return &SchemaError{Msg: "the schema is empty", Err: ErrBadSchema}
+	}
+	return &SchemaError{Msg: strings.TrimPrefix(err.Error(), "json: "), Err: ErrBadSchema}
+}
+
+// LoadSchema reads a schema from a .json, .yaml or .yml file.  Dictionaries
+// named in the schema are found relative to the file.
+func LoadSchema(fname string) (*Schema, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSchema(b)
+	if err != nil {
+		var se *SchemaError
+		if errors.As(err, &se) {
+			se.File = fname
+		}
+		return nil, err
+	}
+	s.dir = filepath.Dir(fname)
+	return s, nil
+}
+
+// GenSchema writes the data described by the schema to w as it is
+// generated, as GenTo does.
+func GenSchema(w io.Writer, s *Schema) error {
+	return defaultGenerator().GenSchema(w, s)
+}
+
+func (g *Generator) GenSchema(w io.Writer, s *Schema) error {
+	g = g.forSchema(s)
+	cts, tw, err := g.compileSchema(s)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err := g.genTables(&output{w: bw}, s, cts, tw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// GenSchemaFile writes the data described by the schema in the file to w.
+func GenSchemaFile(w io.Writer, fname string) error {
+	return defaultGenerator().GenSchemaFile(w, fname)
+}
+
+func (g *Generator) GenSchemaFile(w io.Writer, fname string) error {
+	s, err := LoadSchema(fname)
+	if err != nil {
+		return err
+	}
+	return g.GenSchema(w, s)
+}
+
+// ValidateSchema checks that the schema can be generated: that it is well
+// formed, that its types and filters are known, that its tables do not refer
+// to each other in a cycle and that the options are valid for a row.  The
+// error is a *SchemaError that says where in the schema the problem is.
+func ValidateSchema(s *Schema) error {
+	return defaultGenerator().ValidateSchema(s)
+}
+
+func (g *Generator) ValidateSchema(s *Schema) error {
+	g = g.forSchema(s).withSeed(0)
+	g.count = 1
+	cts, tw, err := g.compileSchema(s)
+	if err != nil {
+		return err
+	}
+	return g.genTables(&output{w: ioutil.Discard}, s, cts, tw)
+}
+
+// forSchema returns a copy of the generator for one call of GenSchema and the
+// like.  The elements which give the keys of other tables are registered on
+// the copy.
+func (g *Generator) forSchema(s *Schema) *Generator {
+	g = g.begin().withRegistry()
+	if s.Seed != nil {
+		g = g.withSeed(*s.Seed)
+	}
+	if s.dir != "" {
+		g.dir = s.dir
+	}
+	return g
+}
+
+// tableWriter writes the rows of tables in an output format.  root is the
+// name of the enclosing element for a schema with tables, and empty for a
+// single table.
+type tableWriter interface {
+	begin(out *output, root string)
+	header(out *output, ct *compiledTable)
+	row(out *output, ct *compiledTable, r int, values []string, nulls []bool)
+	footer(out *output, ct *compiledTable)
+	end(out *output, root string)
+}
+
+var tableWriters = map[string]tableWriter{
+	"csv":   csvTable{},
+	"json":  jsonTable{},
+	"jsonl": jsonTable{lines: true},
+	"xml":   xmlTable{},
+	"sql":   sqlTable{},
+}
+
+// numberTypes are the element types whose values are numbers
+var numberTypes = map[string]bool{"int": true, "float": true, "seq": true}
+
+// jsonNumber matches the numbers of JSON, which SQL takes as they are too
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// identifier matches the names which can be used for fields and tables
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// compiledTable is a table as a block whose body has an element for each
+// field.  The elements and options are given positions which index paths,
+// so that errors can say which part of the schema they are from.
+type compiledTable struct {
+	*Table
+	block   *BlockNode
+	paths   []string
+	numbers []bool //fields whose values are written as numbers if they are
+
+	refs  []tableRef  //fields which refer to other tables
+	per   *keyElement //the field with per, if any
+	keep  []bool      //fields other tables refer to
+	keys  [][]string  //the values of the fields in keep
+	index int         //place of the table in the output
+	multi bool        //whether the table is one of the tables of a schema
+	rows  int         //rows written so far
+}
+
+func (ct *compiledTable) pos(path string) Pos {
+	ct.paths = append(ct.paths, path)
+	return Pos(len(ct.paths) - 1)
+}
+
+// error gives a *SchemaError at the part of the schema which the error of an
+// element is from.
+func (ct *compiledTable) error(err error) error {
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Offset >= len(ct.paths) {
+		return err
+	}
+	return &SchemaError{Path: ct.paths[pe.Offset], Msg: pe.Msg, Err: pe.Kind}
+}
+
+// compileSchema checks the schema and turns its tables into blocks, in the
+// order they are to be generated in.
+func (g *Generator) compileSchema(s *Schema) ([]*compiledTable, tableWriter, error) {
+	bad := func(p, format string, args ...interface{}) error {
+		return &SchemaError{Path: p, Msg: fmt.Sprintf(format, args...), Err: ErrBadSchema}
+	}
+	format := strings.ToLower(s.Format)
+	if format == "" {
+		format = "csv"
+	}
+	tw, ok := tableWriters[format]
+	if !ok {
+		return nil, nil, bad("format", "unknown format %q, expected csv, json, jsonl, xml or sql", s.Format)
+	}
+
+	names := make([]string, 0, len(s.Dictionaries))
+	known := make(map[string]bool)
+	for name, fnames := range s.Dictionaries {
+		if name == "" || len(fnames) == 0 {
+			return nil, nil, bad("dictionaries", "expected name: file")
+		}
+		names = append(names, name)
+		known[strings.ToLower(name)] = true
+	}
+	sort.Strings(names)
+
+	var cts []*compiledTable
+	if len(s.Tables) == 0 {
+		if (format == "xml" || format == "sql") && s.Name == "" {
+			return nil, nil, bad("name", "a name is needed for the %s format", format)
+		}
+		ct, err := g.compileTable(&s.Table, "", known)
+		if err != nil {
+			return nil, nil, err
+		}
+		cts = append(cts, ct)
+	} else {
+		if len(s.Fields) > 0 || s.Count != 0 {
+			return nil, nil, bad("tables", "the fields and count are given for each of the tables when there are tables")
+		}
+		if s.Name != "" && !identifier.MatchString(s.Name) {
+			return nil, nil, bad("name", "%q is not a valid name, expected letters, digits and _", s.Name)
+		}
+		seen := make(map[string]bool)
+		for i := range s.Tables {
+			t := &s.Tables[i]
+			path := fmt.Sprintf("tables[%d].", i)
+			if t.Name == "" {
+				return nil, nil, bad(path+"name", "a name is needed")
+			}
+			if seen[strings.ToLower(t.Name)] {
+				return nil, nil, bad(path+"name", "the name %q is used twice", t.Name)
+			}
+			seen[strings.ToLower(t.Name)] = true
+
+			ct, err := g.compileTable(t, path, known)
+			if err != nil {
+				return nil, nil, err
+			}
+			ct.multi = true
+			cts = append(cts, ct)
+		}
+	}
+
+	if err := g.resolveRefs(cts); err != nil {
+		return nil, nil, err
+	}
+	cts, err := orderTables(cts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, ct := range cts {
+		ct.index = i
+		//fields which refer to numbers are numbers too
+		for _, r := range ct.refs {
+			ct.numbers[r.field] = r.ke.parent.numbers[r.ke.field]
+		}
+		opts := &ct.block.Options.Options
+		for _, name := range names {
+			args := append([]string{name}, s.Dictionaries[name]...)
+			*opts = append(*opts, Option{Pos: ct.pos("dictionaries." + name), Key: "dictionary", Args: args})
+		}
+	}
+	return cts, tw, nil
+}
+
+// compileTable checks a table and turns it into a block.  path is put before
+// the paths of errors.  known are element types which will be registered by
+// the time the block is generated.
+func (g *Generator) compileTable(t *Table, path string, known map[string]bool) (*compiledTable, error) {
+	ct := &compiledTable{Table: t, paths: []string{strings.TrimSuffix(path, ".")}}
+	bad := func(p, format string, args ...interface{}) error {
+		return &SchemaError{Path: p, Msg: fmt.Sprintf(format, args...), Err: ErrBadSchema}
+	}
+
+	if t.Name != "" && !identifier.MatchString(t.Name) {
+		return nil, bad(path+"name", "%q is not a valid name, expected letters, digits and _", t.Name)
+	}
+	count := t.Count
+	if count < 0 {
+		return nil, bad(path+"count", "expected a count of at least 1, received %d", count)
+	}
+	if count == 0 {
+		count = 1
+	}
+	if len(t.Fields) == 0 {
+		return nil, bad(path+"fields", "expected at least one field")
+	}
+	ct.keep = make([]bool, len(t.Fields))
+	ct.keys = make([][]string, len(t.Fields))
+
+	ct.block = &BlockNode{Options: &OptionsNode{Options: []Option{
+		{Pos: ct.pos(path + "count"), Key: "count", Value: strconv.Itoa(count)},
+		{Key: "separator"},
+		{Key: "lastseparator"},
+	}}}
+
+	seen := make(map[string]bool)
+	for i, f := range t.Fields {
+		fp := fmt.Sprintf("%sfields[%d]", path, i)
+		if f.Name == "" {
+			return nil, bad(fp+".name", "a name is needed")
+		}
+		if !identifier.MatchString(f.Name) {
+			return nil, bad(fp+".name", "%q is not a valid name, expected letters, digits and _", f.Name)
+		}
+		name := strings.ToLower(f.Name)
+		if seen[name] {
+			return nil, bad(fp+".name", "the name %q is used twice", f.Name)
+		}
+		seen[name] = true
+
+		if f.Null < 0 || f.Null > 1 {
+			return nil, bad(fp+".null", "expected a probability from 0 to 1, received %v", f.Null)
+		}
+
+		typ := strings.ToLower(strings.TrimSpace(f.Type))
+		var el *ElementNode
+		switch {
+		case f.Ref != "":
+			if typ != "" {
+				return nil, bad(fp+".type", "a type can not be given with ref")
+			}
+			ke := &keyElement{per: f.Per}
+			if f.Per != nil {
+				if err := g.checkPer(ct, f, fp); err != nil {
+					return nil, err
+				}
+				ct.per = ke
+			}
+			//the element is registered for the schema under the name of the
+			//field, which can not be the name of another element
+			typ = strings.ToLower(t.Name + "." + f.Name)
+			g.Register(typ, ke)
+			el = &ElementNode{Pos: ct.pos(fp + ".ref"), Name: typ, As: name}
+			ct.refs = append(ct.refs, tableRef{field: i, ref: f.Ref, path: fp + ".ref", ke: ke})
+		case f.Per != nil:
+			return nil, bad(fp+".per", "per is only given with ref")
+		case typ == "":
+			return nil, bad(fp+".type", "a type is needed")
+		default:
+			if _, ok := g.lookup(typ); !ok && !known[typ] {
+				return nil, &SchemaError{Path: fp + ".type", Msg: fmt.Sprintf("%q", f.Type), Err: ErrUnknownElement}
+			}
+			el = &ElementNode{Pos: ct.pos(fp), Name: typ, As: name}
+		}
+		keys := make([]string, 0, len(f.Options))
+		for k := range f.Options {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			op := fp + ".options." + k
+			o, ok, err := optionFrom(k, f.Options[k])
+			if err != nil {
+				return nil, &SchemaError{Path: op, Msg: err.Error(), Err: ErrBadOptionValue}
+			}
+			if ok {
+				o.Pos = ct.pos(op)
+				el.Options = append(el.Options, o)
+			}
+		}
+		for j, s := range f.Filters {
+			fip := fmt.Sprintf("%s.filters[%d]", fp, j)
+			o, err := parseFilter(s)
+			if err != nil {
+				return nil, &SchemaError{Path: fip, Msg: err.Error(), Err: ErrBadOptionValue}
+			}
+			if _, ok := g.lookupFilter(o.Key); !ok {
+				return nil, &SchemaError{Path: fip, Msg: fmt.Sprintf("unknown filter %q", o.Key), Err: ErrBadOptionValue}
+			}
+			o.Pos = ct.pos(fip)
+			el.Options = append(el.Options, o)
+		}
+
+		ct.block.Body = append(ct.block.Body, el)
+		ct.numbers = append(ct.numbers, numberTypes[typ])
+	}
+	return ct, nil
+}
+
+// optionFrom makes an element option from a value of the options of a field.
+// false gives no option.
+func optionFrom(key string, v interface{}) (Option, bool, error) {
+	o := Option{Key: strings.ToLower(key)}
+	switch v := v.(type) {
+	case nil:
+		return o, true, nil
+	case bool:
+		return o, v, nil
+	case []interface{}:
+		for _, a := range v {
+			s, ok := scalarString(a)
+			if !ok {
+				return o, false, fmt.Errorf("expected a list of values, received %v", v)
+			}
+			o.Args = append(o.Args, s)
+		}
+		o.Value = strings.Join(o.Args, ":")
+		return o, true, nil
+	case []string:
+		o.Args = append(o.Args, v...)
+		o.Value = strings.Join(o.Args, ":")
+		return o, true, nil
+	}
+	s, ok := scalarString(v)
+	if !ok {
+		return o, false, fmt.Errorf("expected a value, a list of values or true, received %v", v)
+	}
+	o.Value, o.Args = s, []string{s}
+	return o, true, nil
+}
+
+// scalarString gives a string, number or bool as text
+func scalarString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, bool:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// parseFilter parses a filter as it is written in a template, e.g.
+// "replace: a: b".
+func parseFilter(s string) (Option, error) {
+	opts, err := newParser(s, DEFAULT).parseList(item{}, "", ErrBadOptionValue)
+	if err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			return Option{}, errors.New(pe.Msg)
+		}
+		return Option{}, err
+	}
+	if len(opts) != 1 || opts[0].Key == "" {
+		return Option{}, fmt.Errorf("expected one filter, e.g. \"truncate: 5\", received %q", s)
+	}
+	return opts[0], nil
+}
+
+// genTables writes the tables in the order they were compiled in.
+func (g *Generator) genTables(out *output, s *Schema, cts []*compiledTable, tw tableWriter) error {
+	root := ""
+	if len(s.Tables) > 0 {
+		if root = s.Name; root == "" {
+			root = "data"
+		}
+	}
+	tw.begin(out, root)
+	for _, ct := range cts {
+		if err := g.genTable(out, ct, tw); err != nil {
+			return err
+		}
+	}
+	tw.end(out, root)
+	return out.err
+}
+
+// genTable writes the rows of a table.  The block writes nothing itself; the
+// values of each row are given to the table writer as they are, so refs see
+// them unescaped.  The values of the fields which other tables refer to are
+// kept.
+func (g *Generator) genTable(out *output, ct *compiledTable, tw tableWriter) error {
+	count := g.count
+	if ct.per != nil {
+		//the count comes from the rows of the table referred to and is not
+		//overridden
+		ct.block.Options.Options[0].Value = strconv.Itoa(ct.per.draw(g.rand))
+		count = 0
+	}
+
+	tw.header(out, ct)
+	nulls := make([]bool, len(ct.Fields))
+	ct.rows = 0
+	rows := &output{w: ioutil.Discard, endRow: func(values []string) error {
+		for i, f := range ct.Fields {
+			nulls[i] = f.Null > 0 && g.rand.Float64() < f.Null
+			if ct.keep[i] && !nulls[i] {
+				ct.keys[i] = append(ct.keys[i], values[i])
+			}
+		}
+		tw.row(out, ct, ct.rows, values, nulls)
+		ct.rows++
+		return out.err
+	}}
+	if err := g.evalBlock(rows, ct.block, DEFAULT, nil, nil, count); err != nil {
+		return ct.error(err)
+	}
+	tw.footer(out, ct)
+	return out.err
+}
+
+// isNumber tells whether the value of field i is written as a number
+func (ct *compiledTable) isNumber(i int, v string) bool {
+	return ct.numbers[i] && jsonNumber.MatchString(v)
+}
+
+// indent gives the indent of the rows of a table
+func (ct *compiledTable) indent() string {
+	if ct.multi {
+		return "    "
+	}
+	return "  "
+}
+
+// csvTable writes a header line and a line for each row.  The tables of a
+// schema are separated by an empty line.
+type csvTable struct{}
+
+func (csvTable) begin(out *output, root string) {}
+
+func (csvTable) header(out *output, ct *compiledTable) {
+	if ct.index > 0 {
+		out.WriteString("\n")
+	}
+	for i, f := range ct.Fields {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(csvEscape(f.Name))
+	}
+	out.WriteString("\n")
+}
+
+func (csvTable) row(out *output, ct *compiledTable, r int, values []string, nulls []bool) {
+	for i, v := range values {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		if !nulls[i] {
+			out.WriteString(csvEscape(v))
+		}
+	}
+	out.WriteString("\n")
+}
+
+func (csvTable) footer(out *output, ct *compiledTable) {}
+
+func (csvTable) end(out *output, root string) {}
+
+// jsonTable writes an array with an object for each row, or an object a line
+// for jsonl.  The tables of a schema are the arrays of an object, by name;
+// in jsonl their rows follow one another.
+type jsonTable struct {
+	lines bool
+}
+
+func (jt jsonTable) begin(out *output, root string) {
+	if !jt.lines && root != "" {
+		out.WriteString("{")
+	}
+}
+
+func (jt jsonTable) header(out *output, ct *compiledTable) {
+	switch {
+	case jt.lines:
+	case ct.multi:
+		if ct.index > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n  \"" + jsonEscape(ct.Name) + "\": [")
+	default:
+		out.WriteString("[")
+	}
+}
+
+func (jt jsonTable) row(out *output, ct *compiledTable, r int, values []string, nulls []bool) {
+	if !jt.lines {
+		if r > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n" + ct.indent())
+	}
+	out.WriteString("{")
+	for i, v := range values {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(`"` + jsonEscape(ct.Fields[i].Name) + `": `)
+		switch {
+		case nulls[i]:
+			out.WriteString("null")
+		case ct.isNumber(i, v):
+			out.WriteString(v)
+		default:
+			out.WriteString(`"` + jsonEscape(v) + `"`)
+		}
+	}
+	out.WriteString("}")
+	if jt.lines {
+		out.WriteString("\n")
+	}
+}
+
+func (jt jsonTable) footer(out *output, ct *compiledTable) {
+	if jt.lines {
+		return
+	}
+	if ct.rows > 0 {
+		out.WriteString("\n" + ct.indent()[2:])
+	}
+	out.WriteString("]")
+	if !ct.multi {
+		out.WriteString("\n")
+	}
+}
+
+func (jt jsonTable) end(out *output, root string) {
+	if !jt.lines && root != "" {
+		out.WriteString("\n}\n")
+	}
+}
+
+// xmlTable writes <name><row><field>value</field>...</row>...</name>, within
+// <root> for the tables of a schema.  Null values are left out.
+type xmlTable struct{}
+
+func (xmlTable) begin(out *output, root string) {
+	if root != "" {
+		out.WriteString("<" + root + ">\n")
+	}
+}
+
+func (xmlTable) header(out *output, ct *compiledTable) {
+	out.WriteString(ct.indent()[2:] + "<" + ct.Name + ">\n")
+}
+
+func (xmlTable) row(out *output, ct *compiledTable, r int, values []string, nulls []bool) {
+	out.WriteString(ct.indent() + "<row>")
+	for i, v := range values {
+		if nulls[i] {
+			continue
+		}
+		name := ct.Fields[i].Name
+		out.WriteString("<" + name + ">" + xmlEscape(v) + "</" + name + ">")
+	}
+	out.WriteString("</row>\n")
+}
+
+func (xmlTable) footer(out *output, ct *compiledTable) {
+	out.WriteString(ct.indent()[2:] + "</" + ct.Name + ">\n")
+}
+
+func (xmlTable) end(out *output, root string) {
+	if root != "" {
+		out.WriteString("</" + root + ">\n")
+	}
+}
+
+// sqlTable writes an INSERT statement for each row
+type sqlTable struct{}
+
+func (sqlTable) begin(out *output, root string) {}
+
+func (sqlTable) header(out *output, ct *compiledTable) {}
+
+func (sqlTable) row(out *output, ct *compiledTable, r int, values []string, nulls []bool) {
+	out.WriteString("INSERT INTO " + ct.Name + " (")
+	for i, f := range ct.Fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(f.Name)
+	}
+	out.WriteString(") VALUES (")
+	for i, v := range values {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		switch {
+		case nulls[i]:
+			out.WriteString("NULL")
+		case ct.isNumber(i, v):
+			out.WriteString(v)
+		default:
+			out.WriteString("'" + sqlEscape(v) + "'")
+		}
+	}
+	out.WriteString(");\n")
+}
+
+func (sqlTable) footer(out *output, ct *compiledTable) {}
+
+func (sqlTable) end(out *output, root string) {}
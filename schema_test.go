@@ -0,0 +1,158 @@
+package datagen
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const peopleSchema = `
+name: people
+count: 3
+fields:
+  - name: id
+    type: seq
+  - name: first
+    type: firstname
+    filters: [title]
+  - name: email
+    type: email
+    options: {from: first, domain: example.com}
+  - name: note
+    type: choice
+    options: {values: "a b,c\"d,e'f"}
+`
+
+func Test_GenSchema_Formats(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		exp    string
+	}{
+		{"csv", "id,first,email,note\n1,Aaron,aaron@example.com,a b\n2,Abdul,abdul@example.com,\"c\"\"d\"\n3,Abe,abe@example.com,e'f\n"},
+		{"json", "[\n" +
+			`  {"id": 1, "first": "Aaron", "email": "aaron@example.com", "note": "a b"},` + "\n" +
+			`  {"id": 2, "first": "Abdul", "email": "abdul@example.com", "note": "c\"d"},` + "\n" +
+			`  {"id": 3, "first": "Abe", "email": "abe@example.com", "note": "e'f"}` + "\n]\n"},
+		{"jsonl", `{"id": 1, "first": "Aaron", "email": "aaron@example.com", "note": "a b"}` + "\n" +
+			`{"id": 2, "first": "Abdul", "email": "abdul@example.com", "note": "c\"d"}` + "\n" +
+			`{"id": 3, "first": "Abe", "email": "abe@example.com", "note": "e'f"}` + "\n"},
+		{"xml", "<people>\n" +
+			"  <row><id>1</id><first>Aaron</first><email>aaron@example.com</email><note>a b</note></row>\n" +
+			"  <row><id>2</id><first>Abdul</first><email>abdul@example.com</email><note>c&#34;d</note></row>\n" +
+			"  <row><id>3</id><first>Abe</first><email>abe@example.com</email><note>e&#39;f</note></row>\n</people>\n"},
+		{"sql", "INSERT INTO people (id, first, email, note) VALUES (1, 'Aaron', 'aaron@example.com', 'a b');\n" +
+			"INSERT INTO people (id, first, email, note) VALUES (2, 'Abdul', 'abdul@example.com', 'c\"d');\n" +
+			"INSERT INTO people (id, first, email, note) VALUES (3, 'Abe', 'abe@example.com', 'e''f');\n"},
+	} {
+		s, err := ParseSchema([]byte(peopleSchema + "format: " + tc.format + "\n"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var sb strings.Builder
+		if err := NewGenerator(1).GenSchema(&sb, s); err != nil {
+			t.Errorf("FAIL. Unexpected error for %s: %v", tc.format, err)
+		} else if sb.String() != tc.exp {
+			t.Errorf("FAIL. Expected %q for %s. Received %q.", tc.exp, tc.format, sb.String())
+		} else {
+			t.Logf("PASS. Expected %q for %s. Received %q.", tc.exp, tc.format, sb.String())
+		}
+	}
+}
+
+// the schema gives the same data as the template it stands for
+func Test_GenSchema_SameAsTemplate(t *testing.T) {
+	js := `{"count": 5, "seed": 7, "fields": [
+		{"name": "name", "type": "firstname", "options": {"random": true, "regex": "^[A-M]"}, "filters": ["lower", "truncate: 3"]},
+		{"name": "age", "type": "int", "options": {"min": 18, "max": 90}},
+		{"name": "copy", "type": "ref", "options": {"ref": "name"}}
+	]}`
+	s, err := ParseSchema([]byte(js))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var sb strings.Builder
+	if err := GenSchema(&sb, s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tmpl := "{{{ [[[ count: 5 | seed: 7 ]]] {{ firstname as name | random | regex: ^[A-M] | lower | truncate: 3 }},{{ int | min: 18 | max: 90 }},{{ ref: name }}\n}}}"
+	exp, err := Gen(tmpl, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.TrimPrefix(sb.String(), "name,age,copy\n"); got != exp {
+		t.Errorf("FAIL. Expected %q. Received %q.", exp, got)
+	} else {
+		t.Logf("PASS. Expected %q. Received %q.", exp, got)
+	}
+}
+
+func Test_LoadSchema(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "teams.txt"), []byte("red\nblue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(dir, "teams.yaml")
+	src := "count: 3\ndictionaries: {team: teams.txt}\nfields:\n  - {name: team, type: team, options: {cycle: true}}\n"
+	if err := ioutil.WriteFile(fname, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := GenSchemaFile(&sb, fname); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp := "team\nred\nblue\nred\n"; sb.String() != exp {
+		t.Errorf("FAIL. Expected %q. Received %q.", exp, sb.String())
+	} else {
+		t.Logf("PASS. Expected %q. Received %q.", exp, sb.String())
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := ioutil.WriteFile(bad, []byte("{\n  \"fields\": [\n}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadSchema(bad)
+	var se *SchemaError
+	if !errors.As(err, &se) || se.File != bad || se.Path != "line 3" {
+		t.Errorf("FAIL. Expected an error at line 3 of %s. Received %v.", bad, err)
+	}
+}
+
+func Test_SchemaError(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		kind error
+		path string
+	}{
+		{`{"format": "tsv", "fields": [{"name": "a", "type": "int"}]}`, ErrBadSchema, "format"},
+		{`{"format": "sql", "fields": [{"name": "a", "type": "int"}]}`, ErrBadSchema, "name"},
+		{`{"count": -1, "fields": [{"name": "a", "type": "int"}]}`, ErrBadSchema, "count"},
+		{`{"count": "3"}`, ErrBadSchema, "count"},
+		{`{"cuont": 3}`, ErrBadSchema, ""},
+		{`{"name": "x"}`, ErrBadSchema, "fields"},
+		{`{"fields": [{"name": "a b", "type": "int"}]}`, ErrBadSchema, "fields[0].name"},
+		{`{"fields": [{"name": "a", "type": "int"}, {"name": "A", "type": "int"}]}`, ErrBadSchema, "fields[1].name"},
+		{`{"fields": [{"name": "a"}]}`, ErrBadSchema, "fields[0].type"},
+		{`{"fields": [{"name": "a", "type": "cuntry"}]}`, ErrUnknownElement, "fields[0].type"},
+		{`{"fields": [{"name": "a", "type": "int", "options": {"min": "x"}}]}`, ErrBadOptionValue, "fields[0].options.min"},
+		{`{"fields": [{"name": "a", "type": "int", "options": {"min": {"x": 1}}}]}`, ErrBadOptionValue, "fields[0].options.min"},
+		{`{"fields": [{"name": "a", "type": "int", "filters": ["upper", "nope"]}]}`, ErrBadOptionValue, "fields[0].filters[1]"},
+		{`{"fields": [{"name": "a", "type": "int", "filters": ["truncate: x"]}]}`, ErrBadOptionValue, "fields[0].filters[0]"},
+		{`{"fields": [{"name": "a", "type": "ref", "options": {"ref": "b"}}]}`, ErrBadOptionValue, "fields[0].options.ref"},
+		{"fields:\n  - name: a\n   type: int", ErrBadSchema, "line 3"},
+	} {
+		s, err := ParseSchema([]byte(tc.s))
+		if err == nil {
+			err = ValidateSchema(s)
+		}
+		var se *SchemaError
+		if !errors.As(err, &se) || !errors.Is(err, tc.kind) || se.Path != tc.path {
+			t.Errorf("FAIL. Expected %v at %q for %s. Received %v.", tc.kind, tc.path, tc.s, err)
+		} else {
+			t.Logf("PASS. Expected %v at %q for %s. Received %v.", tc.kind, tc.path, tc.s, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package datagen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// seqOptions are the options of the seq element, e.g.
+// {{ seq | start: 1000 | step: 5 }} or {{ seq | format: "ORD-%06d" | name: orders }}
+type seqOptions struct {
+	Start   int64
+	Step    int64
+	ZeroPad int //the minimum number of digits
+	Prefix  string
+	Format  string //a fmt format for the number, e.g. ORD-%06d
+	Name    string //sequences with a name continue across blocks
+}
+
+// seqElement counts over the rows of a block.
+type seqElement struct{}
+
+func (seqElement) Generate(ctx *Context) (string, error) {
+	o, ok := ctx.State.(*seqOptions)
+	if !ok {
+		o = &seqOptions{Start: 1, Step: 1}
+		if err := ctx.Decode(o); err != nil {
+			return "", err
+		}
+		if o.ZeroPad < 0 {
+			return "", ctx.OptionError("zeropad", "zeropad can not be negative")
+		}
+		if o.Format != "" && strings.Contains(fmt.Sprintf(o.Format, int64(0)), "%!") {
+			return "", ctx.OptionError("format", "format %q is not for a single integer", o.Format)
+		}
+		ctx.State = o
+	}
+
+	n := o.Start + int64(ctx.Row)*o.Step
+	if o.Name != "" {
+		name := strings.ToLower(o.Name)
+		next, ok := ctx.Generator.seqs[name]
+		if !ok {
+			next = o.Start
+		}
+		n = next
+		ctx.Generator.seqs[name] = n + o.Step
+	}
+
+	var s string
+	switch {
+	case o.Format != "":
+		s = fmt.Sprintf(o.Format, n)
+	case o.ZeroPad > 0:
+		s = fmt.Sprintf("%0*d", o.ZeroPad, n)
+	default:
+		s = strconv.FormatInt(n, 10)
+	}
+	return o.Prefix + s, nil
+}
+
+func (seqElement) OptionNames() []string {
+	return optionNames(seqOptions{})
+}
+
+func init() {
+	Register("seq", seqElement{})
+}
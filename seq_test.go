@@ -0,0 +1,71 @@
+package datagen
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_SeqElement(t *testing.T) {
+	for _, tc := range []struct {
+		eb  string
+		exp string
+	}{
+		{"seq", "1,2,3,4"},
+		{"seq | start: 1000 | step: 5", "1000,1005,1010,1015"},
+		{"seq | start: 10 | step: -3", "10,7,4,1"},
+		{"seq | zeropad: 4 | prefix: C", "C0001,C0002,C0003,C0004"},
+		{"seq | start: 42 | format: \"ORD-%06d\"", "ORD-000042,ORD-000043,ORD-000044,ORD-000045"},
+		{"seq | start: 255 | format: %x", "ff,100,101,102"},
+	} {
+		s, err := NewGenerator(1).GenElement(tc.eb, 4)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		if strings.Join(s, ",") != tc.exp {
+			t.Errorf("FAIL. Expected %s for %s. Received %v.", tc.exp, tc.eb, s)
+		} else {
+			t.Logf("PASS. Expected %s for %s. Received %v.", tc.exp, tc.eb, s)
+		}
+	}
+}
+
+func Test_SeqElement_Blocks(t *testing.T) {
+	s := `{{{ [[[ count: 2 ]]] {{ seq | start: 100 }} {{ firstname }} }}}` +
+		`{{{ [[[ count: 3 ]]] {{ seq | name: id | start: 1 }} {{ country }} }}}` +
+		`{{{ [[[ count: 2 ]]] {{ seq | name: ID | start: 500 }} {{ country | regex: ^Z }} }}}`
+
+	exp := "100 AARON\n101 ABDUL\n1 Afghanistan\n2 Albania\n3 Algeria\n4 Zambia\n5 Zimbabwe\n"
+
+	g := NewGenerator(1)
+	gen, err := g.Gen(s, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gen != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v.", exp, gen)
+	} else {
+		t.Logf("PASS. Expected %+v. Received %+v.", exp, gen)
+	}
+
+	//named sequences start again with every call
+	gen, err = g.Gen(s, DEFAULT)
+	if err != nil || gen != exp {
+		t.Errorf("FAIL. Expected %+v again. Received %+v, %v.", exp, gen, err)
+	}
+
+	//sub blocks count again for each row unless named
+	gen, err = g.GenBlock(`{{{ [[[ count: 2 | separator: ';' ]]] {{{ [[[ count: 2 | separator: ',' | lastseparator: '' ]]] {{ seq }}/{{ seq | name: all }} }}} }}}`)
+	exp = "1/1,2/2;1/3,2/4\n"
+	if err != nil || gen != exp {
+		t.Errorf("FAIL. Expected %+v. Received %+v, %v.", exp, gen, err)
+	}
+}
+
+func Test_SeqElement_BadOptions(t *testing.T) {
+	expectBadOption(t,
+		"seq | start: one",
+		"seq | zeropad: -2",
+		"seq | format: \"%s-%s\"",
+	)
+}
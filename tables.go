@@ -0,0 +1,204 @@
+package datagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Range is min..max.  In a schema file it can be written as "1..5", as a
+// single number for exactly that many, or as {min: 1, max: 5}.
+type Range struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (r *Range) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return r.parse(s)
+	}
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		r.Min, r.Max = n, n
+		return nil
+	}
+	type plain Range
+	return json.Unmarshal(b, (*plain)(r))
+}
+
+// parse reads "min..max" or a single number
+func (r *Range) parse(s string) error {
+	min, max := s, s
+	if i := strings.Index(s, ".."); i >= 0 {
+		min, max = s[:i], s[i+2:]
+	}
+	var err1, err2 error
+	r.Min, err1 = strconv.Atoi(strings.TrimSpace(min))
+	r.Max, err2 = strconv.Atoi(strings.TrimSpace(max))
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("expected min..max like 1..5, received %q", s)
+	}
+	return nil
+}
+
+// tableRef is a field which refers to a field of another table
+type tableRef struct {
+	field int    //index of the field
+	ref   string //table.field referred to
+	path  string //of the ref in the schema
+	ke    *keyElement
+}
+
+// keyElement gives the values of a field of another table, for a field with
+// ref.  The other table is generated first.  Without per the values are
+// picked at random; with per each of them is given to min..max rows in turn.
+type keyElement struct {
+	parent *compiledTable
+	field  int
+	per    *Range
+	counts []int //rows left for each value, with per
+	at     int
+}
+
+// draw decides the rows for each value referred to, and gives their total.
+func (ke *keyElement) draw(r *rand.Rand) int {
+	keys := ke.parent.keys[ke.field]
+	ke.counts = make([]int, len(keys))
+	ke.at = 0
+	total := 0
+	for i := range keys {
+		ke.counts[i] = ke.per.Min + r.Intn(ke.per.Max-ke.per.Min+1)
+		total += ke.counts[i]
+	}
+	return total
+}
+
+func (ke *keyElement) Generate(ctx *Context) (string, error) {
+	keys := ke.parent.keys[ke.field]
+	if ke.per == nil {
+		if len(keys) == 0 {
+			return "", ctx.OptionError("ref", "%s.%s has no values to refer to", ke.parent.Name, ke.parent.Fields[ke.field].Name)
+		}
+		return keys[ctx.Rand.Intn(len(keys))], nil
+	}
+	for ke.at < len(ke.counts) && ke.counts[ke.at] == 0 {
+		ke.at++
+	}
+	if ke.at == len(ke.counts) {
+		return "", ctx.OptionError("per", "the rows per row of %s.%s are used up at row %d", ke.parent.Name, ke.parent.Fields[ke.field].Name, ctx.Row+1)
+	}
+	ke.counts[ke.at]--
+	return keys[ke.at], nil
+}
+
+// checkPer checks the per of a field of the table
+func (g *Generator) checkPer(ct *compiledTable, f Field, fp string) error {
+	bad := func(p, format string, args ...interface{}) error {
+		return &SchemaError{Path: p, Msg: fmt.Sprintf(format, args...), Err: ErrBadSchema}
+	}
+	switch {
+	case ct.per != nil:
+		return bad(fp+".per", "per is given for more than one field of the table")
+	case ct.Count != 0:
+		return bad(fp+".per", "the count of the table can not be given with per, as it is the total of the rows per row of %s", f.Ref)
+	case f.Per.Min < 0 || f.Per.Max < f.Per.Min:
+		return bad(fp+".per", "expected min..max with 0 <= min <= max, received %d..%d", f.Per.Min, f.Per.Max)
+	case f.Null > 0:
+		return bad(fp+".null", "a field with per can not be null")
+	}
+	//the values of a field with per repeat by design
+	for k, v := range f.Options {
+		if o, ok, _ := optionFrom(k, v); ok && o.Key == "unique" {
+			return bad(fp+".options."+k, "a field with per can not be unique, as each value is given to min..max rows")
+		}
+	}
+	return nil
+}
+
+// resolveRefs finds the fields which the fields with ref refer to.
+func (g *Generator) resolveRefs(cts []*compiledTable) error {
+	byName := make(map[string]*compiledTable)
+	for _, ct := range cts {
+		byName[strings.ToLower(ct.Name)] = ct
+	}
+	for _, ct := range cts {
+		for _, r := range ct.refs {
+			bad := func(format string, args ...interface{}) error {
+				return &SchemaError{Path: r.path, Msg: fmt.Sprintf(format, args...), Err: ErrBadSchema}
+			}
+			parts := strings.Split(r.ref, ".")
+			if len(parts) != 2 {
+				return bad("expected table.field, received %q", r.ref)
+			}
+			parent, ok := byName[strings.ToLower(parts[0])]
+			if !ok || parts[0] == "" {
+				return bad("there is no table %q", parts[0])
+			}
+			field := -1
+			for i, f := range parent.Fields {
+				if strings.EqualFold(f.Name, parts[1]) {
+					field = i
+				}
+			}
+			if field < 0 {
+				return bad("table %s has no field %q", parent.Name, parts[1])
+			}
+			r.ke.parent, r.ke.field = parent, field
+			parent.keep[field] = true
+		}
+	}
+	return nil
+}
+
+// orderTables sorts the tables so that each comes after the tables it refers
+// to, and otherwise keeps the order they were given in.  Tables which refer
+// to each other, directly or through other tables, are an error.
+func orderTables(cts []*compiledTable) ([]*compiledTable, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*compiledTable]int)
+	var stack, order []*compiledTable
+
+	var visit func(ct *compiledTable) error
+	visit = func(ct *compiledTable) error {
+		state[ct] = visiting
+		stack = append(stack, ct)
+		for _, r := range ct.refs {
+			p := r.ke.parent
+			switch state[p] {
+			case visiting:
+				var names []string
+				for i := len(stack) - 1; i >= 0; i-- {
+					names = append([]string{stack[i].Name}, names...)
+					if stack[i] == p {
+						break
+					}
+				}
+				names = append(names, p.Name)
+				return &SchemaError{Path: r.path, Msg: fmt.Sprintf("the tables refer to each other: %s", strings.Join(names, " -> ")), Err: ErrBadSchema}
+			case 0:
+				if err := visit(p); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[ct] = done
+		order = append(order, ct)
+		return nil
+	}
+
+	for _, ct := range cts {
+		if state[ct] == 0 {
+			if err := visit(ct); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return order, nil
+}
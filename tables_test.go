@@ -0,0 +1,246 @@
+package datagen
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const shopSchema = `
+format: json
+tables:
+  - name: order_items
+    fields:
+      - {name: order_id, ref: orders.id, per: 1..3}
+      - {name: qty, type: int, options: {min: 1, max: 9}}
+  - name: orders
+    fields:
+      - {name: id, type: seq, options: {start: 1000}}
+      - {name: customer_id, ref: customers.id, per: 1..5}
+      - {name: coupon, type: pattern, options: {regex: "[A-Z]{4}"}, null: 0.5}
+  - name: customers
+    count: 50
+    fields:
+      - {name: id, type: seq}
+      - {name: name, type: firstname, options: {random: true}}
+      - {name: referrer, ref: customers_old.id, null: 0.3}
+  - name: customers_old
+    count: 5
+    fields:
+      - {name: id, type: seq, options: {start: 900}}
+`
+
+func Test_GenSchema_Tables(t *testing.T) {
+	s, err := ParseSchema([]byte(shopSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var sb strings.Builder
+	if err := NewGenerator(1).GenSchema(&sb, s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type row map[string]interface{}
+	var data map[string][]row
+	if err := json.Unmarshal([]byte(sb.String()), &data); err != nil {
+		t.Fatalf("FAIL. Expected JSON. Received %v for %s.", err, sb.String())
+	}
+
+	//every key refers to a row which exists, as many times as per allows
+	children := func(child, field, parent string, min, max int) {
+		counts := make(map[float64]int)
+		for _, r := range data[parent] {
+			counts[r["id"].(float64)] = 0
+		}
+		for _, r := range data[child] {
+			id, ok := r[field].(float64)
+			if _, exists := counts[id]; !ok || !exists {
+				t.Errorf("FAIL. Expected %s.%s to refer to a row of %s. Received %v.", child, field, parent, r[field])
+				continue
+			}
+			counts[id]++
+		}
+		for id, n := range counts {
+			if n < min || n > max {
+				t.Errorf("FAIL. Expected %d to %d rows of %s for %s %v. Received %d.", min, max, child, parent, id, n)
+			}
+		}
+		t.Logf("PASS. Expected %d rows of %s to refer to %d rows of %s.", len(data[child]), child, len(data[parent]), parent)
+	}
+	children("orders", "customer_id", "customers", 1, 5)
+	children("order_items", "order_id", "orders", 1, 3)
+
+	nulls := 0
+	for _, r := range data["customers"] {
+		if r["referrer"] == nil {
+			nulls++
+		} else if id := r["referrer"].(float64); id < 900 || id > 904 {
+			t.Errorf("FAIL. Expected a referrer of customers_old. Received %v.", id)
+		}
+	}
+	if nulls == 0 || nulls == len(data["customers"]) {
+		t.Errorf("FAIL. Expected some of the referrers to be null. Received %d nulls of %d.", nulls, len(data["customers"]))
+	}
+
+	//tables come after the tables they refer to
+	last := -1
+	for _, name := range []string{"customers_old", "customers", "orders", "order_items"} {
+		i := strings.Index(sb.String(), `"`+name+`": [`)
+		if i < last {
+			t.Errorf("FAIL. Expected %s after the tables it refers to. Received %s.", name, sb.String()[:200])
+		}
+		last = i
+	}
+}
+
+func Test_GenSchema_Nulls(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		exp    string
+	}{
+		{"csv", "id,note\n1,\n"},
+		{"json", "[\n  {\"id\": 1, \"note\": null}\n]\n"},
+		{"xml", "<notes>\n  <row><id>1</id></row>\n</notes>\n"},
+		{"sql", "INSERT INTO notes (id, note) VALUES (1, NULL);\n"},
+	} {
+		s, err := ParseSchema([]byte("name: notes\nformat: " + tc.format + "\nfields:\n  - {name: id, type: seq}\n  - {name: note, type: text, null: 1}\n"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var sb strings.Builder
+		if err := GenSchema(&sb, s); err != nil || sb.String() != tc.exp {
+			t.Errorf("FAIL. Expected %q for %s. Received %q, %v.", tc.exp, tc.format, sb.String(), err)
+		} else {
+			t.Logf("PASS. Expected %q for %s. Received %q.", tc.exp, tc.format, sb.String())
+		}
+	}
+}
+
+func Test_GenSchema_TablesFormats(t *testing.T) {
+	src := `{"tables": [
+		{"name": "orders", "fields": [{"name": "id", "type": "seq"}, {"name": "customer", "ref": "customers.name", "per": 1}]},
+		{"name": "customers", "count": 2, "fields": [{"name": "name", "type": "firstname"}]}
+	]}`
+	for _, tc := range []struct {
+		format string
+		exp    string
+	}{
+		{"csv", "name\nAARON\nABDUL\n\nid,customer\n1,AARON\n2,ABDUL\n"},
+		{"json", "{\n  \"customers\": [\n    {\"name\": \"AARON\"},\n    {\"name\": \"ABDUL\"}\n  ],\n  \"orders\": [\n" +
+			"    {\"id\": 1, \"customer\": \"AARON\"},\n    {\"id\": 2, \"customer\": \"ABDUL\"}\n  ]\n}\n"},
+		{"jsonl", "{\"name\": \"AARON\"}\n{\"name\": \"ABDUL\"}\n{\"id\": 1, \"customer\": \"AARON\"}\n{\"id\": 2, \"customer\": \"ABDUL\"}\n"},
+		{"xml", "<data>\n  <customers>\n    <row><name>AARON</name></row>\n    <row><name>ABDUL</name></row>\n  </customers>\n" +
+			"  <orders>\n    <row><id>1</id><customer>AARON</customer></row>\n    <row><id>2</id><customer>ABDUL</customer></row>\n  </orders>\n</data>\n"},
+		{"sql", "INSERT INTO customers (name) VALUES ('AARON');\nINSERT INTO customers (name) VALUES ('ABDUL');\n" +
+			"INSERT INTO orders (id, customer) VALUES (1, 'AARON');\nINSERT INTO orders (id, customer) VALUES (2, 'ABDUL');\n"},
+	} {
+		s, err := ParseSchema([]byte(src))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		s.Format = tc.format
+		var sb strings.Builder
+		if err := GenSchema(&sb, s); err != nil || sb.String() != tc.exp {
+			t.Errorf("FAIL. Expected %q for %s. Received %q, %v.", tc.exp, tc.format, sb.String(), err)
+		} else {
+			t.Logf("PASS. Expected %q for %s. Received %q.", tc.exp, tc.format, sb.String())
+		}
+	}
+}
+
+func Test_Range(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		exp Range
+	}{
+		{`"1..5"`, Range{1, 5}},
+		{`" 0 .. 2 "`, Range{0, 2}},
+		{`3`, Range{3, 3}},
+		{`"4"`, Range{4, 4}},
+		{`{"min": 2, "max": 7}`, Range{2, 7}},
+	} {
+		var r Range
+		if err := json.Unmarshal([]byte(tc.s), &r); err != nil || r != tc.exp {
+			t.Errorf("FAIL. Expected %v for %s. Received %v, %v.", tc.exp, tc.s, r, err)
+		} else {
+			t.Logf("PASS. Expected %v for %s. Received %v.", tc.exp, tc.s, r)
+		}
+	}
+	var r Range
+	if err := json.Unmarshal([]byte(`"1-5"`), &r); err == nil {
+		t.Errorf("FAIL. Expected an error for 1-5.")
+	}
+}
+
+func Test_SchemaError_Tables(t *testing.T) {
+	table := func(name, fields string) string {
+		return `{"name": "` + name + `", "fields": [` + fields + `]}`
+	}
+	tables := func(ts ...string) string {
+		return `{"tables": [` + strings.Join(ts, ", ") + `]}`
+	}
+	for _, tc := range []struct {
+		s    string
+		kind error
+		path string
+		msg  string
+	}{
+		{tables(table("a", `{"name": "x", "ref": "b.x"}`), table("b", `{"name": "x", "ref": "c.x"}`), table("c", `{"name": "x", "ref": "b.x"}`)),
+			ErrBadSchema, "tables[2].fields[0].ref", "b -> c -> b"},
+		{tables(table("a", `{"name": "x", "ref": "a.x"}`)), ErrBadSchema, "tables[0].fields[0].ref", "a -> a"},
+		{tables(table("a", `{"name": "x", "ref": "b"}`)), ErrBadSchema, "tables[0].fields[0].ref", "expected table.field"},
+		{tables(table("a", `{"name": "x", "ref": "b.x"}`)), ErrBadSchema, "tables[0].fields[0].ref", "no table"},
+		{tables(table("a", `{"name": "x", "ref": "b.y"}`), table("b", `{"name": "x", "type": "int"}`)), ErrBadSchema, "tables[0].fields[0].ref", "no field"},
+		{tables(table("a", `{"name": "x", "type": "int", "ref": "b.x"}`)), ErrBadSchema, "tables[0].fields[0].type", "type"},
+		{tables(table("a", `{"name": "x", "type": "int", "per": "1..2"}`)), ErrBadSchema, "tables[0].fields[0].per", "only given with ref"},
+		{tables(table("b", `{"name": "x", "type": "int"}`), table("a", `{"name": "x", "ref": "b.x", "per": "3..2"}`)), ErrBadSchema, "tables[1].fields[0].per", "min <= max"},
+		{tables(table("b", `{"name": "x", "type": "int"}`), table("a", `{"name": "x", "ref": "b.x", "per": 1}, {"name": "y", "ref": "b.x", "per": 1}`)), ErrBadSchema, "tables[1].fields[1].per", "more than one"},
+		{tables(table("b", `{"name": "x", "type": "int"}`), `{"name": "a", "count": 3, "fields": [{"name": "x", "ref": "b.x", "per": 1}]}`), ErrBadSchema, "tables[1].fields[0].per", "count"},
+		{tables(table("b", `{"name": "x", "type": "int"}`), table("a", `{"name": "x", "ref": "b.x", "per": 1, "null": 0.5}`)), ErrBadSchema, "tables[1].fields[0].null", "null"},
+		{tables(table("b", `{"name": "x", "type": "int"}`), table("a", `{"name": "x", "ref": "b.x", "per": 2, "options": {"unique": true}}`)), ErrBadSchema, "tables[1].fields[0].options.unique", "unique"},
+		{tables(table("a", `{"name": "x", "type": "int", "null": 1.5}`)), ErrBadSchema, "tables[0].fields[0].null", "probability"},
+		{tables(table("b", `{"name": "x", "type": "int", "null": 1}`), table("a", `{"name": "x", "ref": "b.x"}`)), ErrBadOptionValue, "tables[1].fields[0].ref", "no values"},
+		{tables(table("a", `{"name": "x", "type": "int"}`), table("A", `{"name": "x", "type": "int"}`)), ErrBadSchema, "tables[1].name", "twice"},
+		{tables(`{"fields": [{"name": "x", "type": "int"}]}`), ErrBadSchema, "tables[0].name", "needed"},
+		{`{"fields": [{"name": "x", "type": "int"}], "tables": [` + table("a", `{"name": "x", "type": "int"}`) + `]}`, ErrBadSchema, "tables", "each of the tables"},
+		{tables(table("a", `{"name": "x", "type": "cuntry"}`)), ErrUnknownElement, "tables[0].fields[0].type", "cuntry"},
+	} {
+		s, err := ParseSchema([]byte(tc.s))
+		if err == nil {
+			err = ValidateSchema(s)
+		}
+		var se *SchemaError
+		if !errors.As(err, &se) || !errors.Is(err, tc.kind) || se.Path != tc.path || !strings.Contains(se.Msg, tc.msg) {
+			t.Errorf("FAIL. Expected %v at %q with %q for %s. Received %v.", tc.kind, tc.path, tc.msg, tc.s, err)
+		} else {
+			t.Logf("PASS. Expected %v at %q for %s. Received %v.", tc.kind, tc.path, tc.s, err)
+		}
+	}
+}
+
+// a per field gives no more values than the rows per row it was drawn for
+func Test_keyElement_UsedUp(t *testing.T) {
+	parent := &compiledTable{Table: &Table{Name: "customers", Fields: []Field{{Name: "id"}}}, keys: [][]string{{"1", "2", "3"}}}
+	ke := &keyElement{parent: parent, per: &Range{2, 2}}
+	if n := ke.draw(NewGenerator(1).rand); n != 6 {
+		t.Fatalf("FAIL. Expected 6 rows. Received %d.", n)
+	}
+	ctx := &Context{Name: "orders.customer_id"}
+	var got []string
+	for ctx.Row = 0; ctx.Row < 6; ctx.Row++ {
+		v, err := ke.Generate(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got = append(got, v)
+	}
+	if strings.Join(got, ",") != "1,1,2,2,3,3" {
+		t.Errorf("FAIL. Expected 1,1,2,2,3,3. Received %v.", got)
+	}
+	if _, err := ke.Generate(ctx); !errors.Is(err, ErrBadOptionValue) {
+		t.Errorf("FAIL. Expected %v once the rows are used up. Received %v.", ErrBadOptionValue, err)
+	} else {
+		t.Logf("PASS. Expected an error once the rows are used up. Received %v.", err)
+	}
+}
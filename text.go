@@ -0,0 +1,101 @@
+package datagen
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// charsets are the named sets of characters for text.  Names can be joined
+// with a +, e.g. lower+numbers.
+var charsets = map[string]string{
+	"letters": letters,
+	"alpha":   letters,
+	"lower":   smallLetters,
+	"upper":   capitalLetters,
+	"numbers": numbers,
+	"digits":  numbers,
+	"alnum":   letters + numbers,
+	"special": specialChars,
+	"all":     letters + numbers + specialChars,
+}
+
+// charsetChars gives the characters of a charset.  Anything which is not a
+// named charset is taken as the characters to use.
+func charsetChars(charset string) string {
+	if charset == "" {
+		return letters
+	}
+	if chars, ok := charsets[strings.ToLower(charset)]; ok {
+		return chars
+	}
+
+	var sb strings.Builder
+	for _, name := range strings.Split(strings.ToLower(charset), "+") {
+		chars, ok := charsets[name]
+		if !ok {
+			return charset
+		}
+		sb.WriteString(chars)
+	}
+	return sb.String()
+}
+
+// randomText gives a string of minSize to maxSize characters chosen from chars.
+func randomText(r *rand.Rand, minSize, maxSize int, chars []rune) string {
+	if maxSize < minSize {
+		maxSize = minSize
+	}
+	size := minSize + r.Intn(maxSize-minSize+1)
+	b := make([]rune, size)
+	for i := range b {
+		b[i] = chars[r.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+// textElement gives random text, e.g. {{ text | minsize: 5 | maxsize: 20 | charset: alnum }}
+type textElement struct{}
+
+type textState struct {
+	opts  TextData
+	chars []rune
+}
+
+func (textElement) Generate(ctx *Context) (string, error) {
+	st, ok := ctx.State.(*textState)
+	if !ok {
+		st = &textState{opts: TextData{MinSize: 5, MaxSize: 10}}
+		if err := ctx.Decode(&st.opts); err != nil {
+			return "", err
+		}
+		//a size given on its own moves the default of the other
+		_, minGiven := ctx.Option("minsize")
+		_, maxGiven := ctx.Option("maxsize")
+		if !minGiven && st.opts.MaxSize < st.opts.MinSize {
+			st.opts.MinSize = st.opts.MaxSize
+		}
+		if !maxGiven && st.opts.MaxSize < st.opts.MinSize {
+			st.opts.MaxSize = st.opts.MinSize
+		}
+		if st.opts.MinSize < 0 {
+			return "", ctx.OptionError("minsize", "size can not be negative")
+		}
+		if st.opts.MaxSize < st.opts.MinSize {
+			return "", ctx.OptionError("maxsize", "maxsize %d is less than minsize %d", st.opts.MaxSize, st.opts.MinSize)
+		}
+		st.chars = []rune(charsetChars(st.opts.Charset))
+		if len(st.chars) == 0 {
+			return "", ctx.OptionError("charset", "no characters to choose from")
+		}
+		ctx.State = st
+	}
+	return randomText(ctx.Rand, st.opts.MinSize, st.opts.MaxSize, st.chars), nil
+}
+
+func (textElement) OptionNames() []string {
+	return optionNames(TextData{})
+}
+
+func init() {
+	Register("text", textElement{})
+}
@@ -0,0 +1,64 @@
+package datagen
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_TextElement(t *testing.T) {
+	for _, tc := range []struct {
+		eb       string
+		min, max int
+		chars    string
+	}{
+		{"text", 5, 10, letters},
+		{"text | minsize: 5 | maxsize: 20 | charset: alnum", 5, 20, letters + numbers},
+		{"text | minsize: 3 | maxsize: 3 | charset: digits", 3, 3, numbers},
+		{"text | maxsize: 2 | charset: lower+numbers", 2, 2, smallLetters + numbers},
+		{"text | minsize: 12 | charset: \"abc123\"", 12, 12, "abc123"},
+		{"text | minsize: 4 | maxsize: 8 | charset: 'äöü|:'", 4, 8, "äöü|:"},
+	} {
+		s, err := NewGenerator(1).GenElement(tc.eb, 20)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.eb, err)
+			continue
+		}
+		if len(s) != 20 {
+			t.Errorf("FAIL. Expected 20 values for %s. Received %d.", tc.eb, len(s))
+		}
+		for _, v := range s {
+			n := len([]rune(v))
+			if n < tc.min || n > tc.max || strings.Trim(v, tc.chars) != "" {
+				t.Errorf("FAIL. Expected %d to %d of %q for %s. Received %q.", tc.min, tc.max, tc.chars, tc.eb, v)
+				break
+			}
+		}
+		t.Logf("Received %v for %s", s, tc.eb)
+	}
+}
+
+func Test_TextElement_Block(t *testing.T) {
+	block := "{{{ [[[ count: 4 | seed: 3 ]]] {{ text | minsize: 8 | maxsize: 8 | charset: upper }} }}}"
+
+	first, err := Gen(block, DEFAULT)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, _ := Gen(block, DEFAULT)
+	if first != second {
+		t.Errorf("FAIL. Expected the same text for the same seed. Received %+v and %+v.", first, second)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(first, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Errorf("FAIL. Expected 4 lines. Received %+v.", first)
+	}
+}
+
+func Test_TextElement_BadOptions(t *testing.T) {
+	expectBadOption(t,
+		"text | minsize: 10 | maxsize: 5",
+		"text | minsize: -1",
+		"text | maxsize: many",
+	)
+}
@@ -0,0 +1,391 @@
+package datagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads the subset of YAML used by schema files: block mappings and
+// sequences by indentation, flow sequences and mappings like [a, b] and
+// {min: 1, max: 5}, quoted and plain scalars, and # comments.  Anchors, tags,
+// multi line scalars and multiple documents are not supported.  Mappings are
+// map[string]interface{}, sequences []interface{} and scalars string,
+// json.Number, bool or nil, so that the result can be given to
+// encoding/json.
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(stripComment(text), " \t\r")
+		content := strings.TrimLeft(text, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, yamlErrorf(i+1, "tabs can not be used for indentation")
+		}
+		lines = append(lines, yamlLine{i + 1, len(text) - len(content), content})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	v, err := p.node(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(lines) {
+		return nil, yamlErrorf(lines[p.i].num, "unexpected indentation")
+	}
+	return v, nil
+}
+
+// yamlError is an error in the YAML at a line
+type yamlError struct {
+	Line int
+	Msg  string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func yamlErrorf(line int, format string, args ...interface{}) error {
+	return &yamlError{line, fmt.Sprintf(format, args...)}
+}
+
+type yamlLine struct {
+	num     int
+	indent  int
+	content string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func isSeqItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+// node reads the mapping or sequence at the indent
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	if isSeqItem(p.lines[p.i].content) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && isSeqItem(p.lines[p.i].content) {
+		l := p.lines[p.i]
+		rest := strings.TrimLeft(strings.TrimPrefix(l.content, "-"), " ")
+		if rest == "" {
+			p.i++
+			v, err := p.child(indent, l.num)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		if _, _, ok := splitKey(rest); ok && !strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, "{") {
+			//a mapping which starts on the line of the dash
+			p.lines[p.i] = yamlLine{l.num, indent + len(l.content) - len(rest), rest}
+			v, err := p.mapping(p.lines[p.i].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		v, err := scalar(rest, l.num)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+		p.i++
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && !isSeqItem(p.lines[p.i].content) {
+		l := p.lines[p.i]
+		key, rest, ok := splitKey(l.content)
+		if !ok {
+			return nil, yamlErrorf(l.num, "expected key: value")
+		}
+		if _, dup := m[key]; dup {
+			return nil, yamlErrorf(l.num, "%q is given twice", key)
+		}
+		p.i++
+		if rest != "" {
+			v, err := scalar(rest, l.num)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		//a sequence can be at the same indent as its key
+		if p.i < len(p.lines) && p.lines[p.i].indent == indent && isSeqItem(p.lines[p.i].content) {
+			v, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		v, err := p.child(indent, l.num)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// child reads the node indented under a line, or nil if there is none
+func (p *yamlParser) child(indent, num int) (interface{}, error) {
+	if p.i >= len(p.lines) || p.lines[p.i].indent <= indent {
+		return nil, nil
+	}
+	return p.node(p.lines[p.i].indent)
+}
+
+// splitKey splits "key: value" at the first colon which is followed by a
+// space or the end of the line and is not within quotes.
+func splitKey(s string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == ':' && (i == len(s)-1 || s[i+1] == ' '):
+			key, err := keyString(strings.TrimSpace(s[:i]))
+			if err != nil {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(s[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// keyString gives the key of a mapping.  Keys are always strings, so that
+// null: 0.5 is the key "null".
+func keyString(s string) (string, error) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		v, err := scalar(s, 0)
+		if err != nil {
+			return "", err
+		}
+		if k, ok := v.(string); ok {
+			return k, nil
+		}
+	}
+	return s, nil
+}
+
+// stripComment removes a # comment which is not within quotes
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// scalar reads a scalar or a flow sequence or mapping
+func scalar(s string, num int) (interface{}, error) {
+	f := &flow{s: s, num: num}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	if f.skipSpace(); f.i < len(f.s) {
+		return nil, yamlErrorf(num, "unexpected %q", f.s[f.i:])
+	}
+	return v, nil
+}
+
+// flow reads values written on one line
+type flow struct {
+	s   string
+	i   int
+	num int
+}
+
+func (f *flow) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *flow) errorf(format string, args ...interface{}) error {
+	return yamlErrorf(f.num, format, args...)
+}
+
+// value reads a value.  Within a flow sequence or mapping, plain scalars end
+// at a comma or closing bracket.
+func (f *flow) value() (interface{}, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		seq := []interface{}{}
+		for {
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return seq, nil
+			}
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			if err := f.next(']'); err != nil {
+				return nil, err
+			}
+			if f.s[f.i-1] == ']' {
+				return seq, nil
+			}
+		}
+	case '{':
+		f.i++
+		m := map[string]interface{}{}
+		for {
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return m, nil
+			}
+			f.skipSpace()
+			start := f.i
+			if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+				if _, err := f.plainOrQuoted(""); err != nil {
+					return nil, err
+				}
+			} else {
+				for f.i < len(f.s) && f.s[f.i] != ':' {
+					f.i++
+				}
+			}
+			k, err := keyString(strings.TrimSpace(f.s[start:f.i]))
+			if err != nil {
+				return nil, err
+			}
+			if f.i >= len(f.s) || f.s[f.i] != ':' {
+				return nil, f.errorf("expected key: value in %q", f.s)
+			}
+			f.i++
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+			if err := f.next('}'); err != nil {
+				return nil, err
+			}
+			if f.s[f.i-1] == '}' {
+				return m, nil
+			}
+		}
+	}
+	return f.plainOrQuoted(",]}")
+}
+
+// next skips the comma after a value, or the closing bracket
+func (f *flow) next(end byte) error {
+	f.skipSpace()
+	if f.i < len(f.s) && (f.s[f.i] == ',' || f.s[f.i] == end) {
+		f.i++
+		return nil
+	}
+	return f.errorf("expected , or %c in %q", end, f.s)
+}
+
+// plainOrQuoted reads a quoted scalar, or a plain one up to one of the stop
+// characters when within a flow, or to the end of the line.
+func (f *flow) plainOrQuoted(stop string) (interface{}, error) {
+	f.skipSpace()
+	inFlow := f.i > 0
+	start := f.i
+	if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+		q := f.s[f.i]
+		for f.i++; f.i < len(f.s); f.i++ {
+			if q == '"' && f.s[f.i] == '\\' {
+				f.i++
+				continue
+			}
+			if f.s[f.i] == q {
+				if q == '\'' && f.i+1 < len(f.s) && f.s[f.i+1] == '\'' {
+					f.i++
+					continue
+				}
+				f.i++
+				quoted := f.s[start:f.i]
+				if q == '\'' {
+					return strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'"), nil
+				}
+				s, err := strconv.Unquote(quoted)
+				if err != nil {
+					return nil, f.errorf("bad string %s", quoted)
+				}
+				return s, nil
+			}
+		}
+		return nil, f.errorf("no closing quote in %q", f.s)
+	}
+
+	for f.i < len(f.s) && !(inFlow && strings.IndexByte(stop, f.s[f.i]) >= 0) {
+		f.i++
+	}
+	return plain(strings.TrimSpace(f.s[start:f.i])), nil
+}
+
+// plain gives the value of a plain scalar
+func plain(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	//numbers are those of JSON, so that large ones are kept exactly
+	if jsonNumber.MatchString(s) {
+		return json.Number(s)
+	}
+	return s
+}
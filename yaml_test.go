@@ -0,0 +1,108 @@
+package datagen
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_parseYAML(t *testing.T) {
+	src := `# people
+name: people
+count: 3
+fields:
+  - name: id
+    type: seq
+  - name: first   # a comment
+    type: firstname
+    options: {random: true, regex: "^A#"}
+    filters: [title, 'truncate: 4']
+  -
+    name: note
+    type: choice
+tags:
+- a
+- "b: c"
+empty:
+`
+	v, err := parseYAML([]byte(src))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := map[string]interface{}{
+		"name":  "people",
+		"count": json.Number("3"),
+		"fields": []interface{}{
+			map[string]interface{}{"name": "id", "type": "seq"},
+			map[string]interface{}{
+				"name":    "first",
+				"type":    "firstname",
+				"options": map[string]interface{}{"random": true, "regex": "^A#"},
+				"filters": []interface{}{"title", "truncate: 4"},
+			},
+			map[string]interface{}{"name": "note", "type": "choice"},
+		},
+		"tags":  []interface{}{"a", "b: c"},
+		"empty": nil,
+	}
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("FAIL. Expected %v. Received %v.", exp, v)
+	} else {
+		t.Logf("PASS. Expected %v. Received %v.", exp, v)
+	}
+}
+
+func Test_parseYAML_Scalars(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		exp interface{}
+	}{
+		{"a: 1.5e3", json.Number("1.5e3")},
+		{"a: -7", json.Number("-7")},
+		{"a: 007", "007"},
+		{"a: yes", "yes"},
+		{"a: False", false},
+		{"a: ~", nil},
+		{`a: "x\ty"`, "x\ty"},
+		{"a: 'it''s'", "it's"},
+		{"a: x # y", "x"},
+		{"a: x#y", "x#y"},
+		{"a: [1, [b, c], {d: e}]", []interface{}{json.Number("1"), []interface{}{"b", "c"}, map[string]interface{}{"d": "e"}}},
+		{"a: []", []interface{}{}},
+		{"a: {null: 0.5, 'x:y': 1}", map[string]interface{}{"null": json.Number("0.5"), "x:y": json.Number("1")}},
+	} {
+		v, err := parseYAML([]byte(tc.s))
+		if err != nil {
+			t.Errorf("FAIL. Unexpected error for %s: %v", tc.s, err)
+			continue
+		}
+		if got := v.(map[string]interface{})["a"]; !reflect.DeepEqual(got, tc.exp) {
+			t.Errorf("FAIL. Expected %#v for %s. Received %#v.", tc.exp, tc.s, got)
+		} else {
+			t.Logf("PASS. Expected %#v for %s. Received %#v.", tc.exp, tc.s, got)
+		}
+	}
+}
+
+func Test_parseYAML_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		line int
+	}{
+		{"a: 1\n  b: 2", 2},
+		{"a: 1\na: 2", 2},
+		{"a:\n  - x\n  y", 3},
+		{"a: [1, 2", 1},
+		{"a: \"x", 1},
+		{"a: 1\n\n\tb: 2", 3},
+	} {
+		_, err := parseYAML([]byte(tc.s))
+		var ye *yamlError
+		if !errors.As(err, &ye) || ye.Line != tc.line {
+			t.Errorf("FAIL. Expected an error at line %d for %q. Received %v.", tc.line, tc.s, err)
+		} else {
+			t.Logf("PASS. Expected an error at line %d for %q. Received %v.", tc.line, tc.s, err)
+		}
+	}
+}